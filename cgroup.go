@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/psutils"
+)
+
+// outputCgroupStat prints the file cache charged to the cgroup next to the
+// cached bytes of the files pgcacher found in it. the cgroup is also charged
+// for files its processes no longer hold, so the numbers rarely match.
+func (pg *pgcacher) outputCgroupStat(cgroup string, stats PcStatusList) {
+	dir, err := psutils.CgroupDir(cgroup)
+	if err != nil {
+		log.Printf("failed to find cgroup, err: %v", err)
+		return
+	}
+
+	cache, err := psutils.CgroupMemoryStat(dir)
+	if err != nil {
+		log.Printf("failed to read memory.stat of %s, err: %v", dir, err)
+		return
+	}
+
+	var cached int64
+	for _, pcs := range stats {
+		cached += cachedSize(pcs)
+	}
+
+	out := pg.reportWriter()
+
+	fmt.Fprintf(out, "cgroup %s (v%d): file cache %s, mapped %s, dirty %s, shmem %s\n",
+		cache.Dir, cache.Version, humanize.IBytes(uint64(cache.Cache)), humanize.IBytes(uint64(cache.Mapped)),
+		humanize.IBytes(uint64(cache.Dirty)), humanize.IBytes(uint64(cache.Shmem)))
+	fmt.Fprintf(out, "pgcacher found %s cached in %d files, %.3f%% of the file cache of the cgroup\n",
+		humanize.IBytes(uint64(cached)), len(stats), percentOf(cached, cache.Cache))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+type fileID struct {
+	dev, inode uint64
+}
+
+// dedupFiles merges the stats of the same file reached through several
+// names, e.g. hardlinks, bind mounts, symlinked dirs and other mount
+// namespaces. the name of our own namespace which is not deleted, then the
+// shortest name is kept, the others are kept as its aliases. the order of
+// the stats is kept.
+func dedupFiles(stats PcStatusList) PcStatusList {
+	byID := make(map[fileID][]int, len(stats))
+	for i, pcs := range stats {
+		if pcs.Inode == 0 {
+			continue
+		}
+		id := fileID{pcs.Dev, pcs.Inode}
+		byID[id] = append(byID[id], i)
+	}
+
+	drop := make(map[int]emptyNull)
+	for _, same := range byID {
+		if len(same) < 2 {
+			continue
+		}
+
+		sort.SliceStable(same, func(a, b int) bool {
+			x, y := stats[same[a]], stats[same[b]]
+			if (x.MountNs == "") != (y.MountNs == "") {
+				return x.MountNs == ""
+			}
+			if x.Deleted != y.Deleted {
+				return !x.Deleted
+			}
+			if len(x.Name) != len(y.Name) {
+				return len(x.Name) < len(y.Name)
+			}
+			return fileKey(x.MountNs, x.Name) < fileKey(y.MountNs, y.Name)
+		})
+
+		keep := &stats[same[0]]
+		for _, i := range same[1:] {
+			alias := stats[i]
+			// the same name measured twice is not an alias of itself.
+			if name := fileKey(alias.MountNs, alias.Name); name != fileKey(keep.MountNs, keep.Name) {
+				keep.Aliases = append(keep.Aliases, name)
+			}
+			keep.Aliases = append(keep.Aliases, alias.Aliases...)
+			keep.Processes = mergeHolders(keep.Processes, alias.Processes)
+			drop[i] = emptyNull{}
+		}
+		sort.Strings(keep.Aliases)
+	}
+	if len(drop) == 0 {
+		return stats
+	}
+
+	out := make(PcStatusList, 0, len(stats)-len(drop))
+	for i, pcs := range stats {
+		if _, ok := drop[i]; !ok {
+			out = append(out, pcs)
+		}
+	}
+	return out
+}
+
+// mergeHolders returns the union of the holders by pid, sorted by pid.
+func mergeHolders(a, b []pcstats.Holder) []pcstats.Holder {
+	if len(b) == 0 {
+		return a
+	}
+
+	seen := make(map[int]emptyNull, len(a)+len(b))
+	out := make([]pcstats.Holder, 0, len(a)+len(b))
+	for _, h := range append(append([]pcstats.Holder(nil), a...), b...) {
+		if _, ok := seen[h.Pid]; ok {
+			continue
+		}
+		seen[h.Pid] = emptyNull{}
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pid < out[j].Pid })
+	return out
+}
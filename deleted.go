@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// the kernel appends it to the links in /proc/<pid>/fd and the paths in
+// /proc/<pid>/maps of the files that have been unlinked.
+const deletedSuffix = " (deleted)"
+
+func isDeleted(fname string) bool {
+	return strings.HasSuffix(fname, deletedSuffix)
+}
+
+// heldFiles is the deleted files of the processes in a mount namespace, by
+// name, to the /proc links of the processes holding them. the files are
+// opened through the links, which only work with our own /proc.
+type heldFiles map[string][]string
+
+// addHeldFile records a link to the deleted file held by a process.
+func (pg *pgcacher) addHeldFile(mntns, fname, link string) {
+	if pg.held == nil {
+		pg.held = make(map[string]heldFiles)
+	}
+	if pg.held[mntns] == nil {
+		pg.held[mntns] = make(heldFiles)
+	}
+	pg.held[mntns][fname] = append(pg.held[mntns][fname], link)
+}
+
+// filterHeldFiles removes the ignored deleted files.
+func (pg *pgcacher) filterHeldFiles() {
+	for _, files := range pg.held {
+		for fname := range files {
+			if pg.ignoreFile(fname) {
+				delete(files, fname)
+			}
+		}
+	}
+}
+
+// measureHeldFiles gets the page cache stats of the deleted files, through
+// the first link that can still be opened.
+func (pg *pgcacher) measureHeldFiles() PcStatusList {
+	mntnss := make([]string, 0, len(pg.held))
+	for mntns := range pg.held {
+		mntnss = append(mntnss, mntns)
+	}
+	sort.Strings(mntnss)
+
+	var stats PcStatusList
+	for _, mntns := range mntnss {
+		files := pg.held[mntns]
+		names := make([]string, 0, len(files))
+		for fname := range files {
+			names = append(names, fname)
+		}
+		stats = append(stats, pg.measureFiles(names, mntns, nil, files)...)
+	}
+	return stats
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// PcStatusDelta is the page cache stats of a file together with the change
+// of its cached pages since a previous sample.
+type PcStatusDelta struct {
+	pcstats.PcStatus
+
+	Delta        int     `json:"delta"`            // cached pages gained, negative when lost
+	PercentDelta float64 `json:"percent_delta"`    // change of the percent of cached pages
+	Rate         float64 `json:"rate"`             // cached bytes gained per second, negative when evicted
+	Change       string  `json:"change,omitempty"` // changeAppeared or changeDisappeared
+}
+
+const (
+	changeAppeared    = "appeared"
+	changeDisappeared = "disappeared"
+)
+
+type PcStatusDeltaList []PcStatusDelta
+
+// newPcStatusDeltaList compares the current stats with the previous ones.
+// when there is no previous stats, the files have no delta. otherwise files
+// only in cur are marked appeared, and files only in prev are appended
+// as disappeared with nothing cached.
+func newPcStatusDeltaList(prev, cur PcStatusList) PcStatusDeltaList {
+	pageSize := int64(os.Getpagesize())
+	last := make(map[string]pcstats.PcStatus, len(prev))
+	for _, pcs := range prev {
+		last[fileKey(pcs.MountNs, pcs.Name)] = pcs
+	}
+
+	deltas := make(PcStatusDeltaList, 0, len(cur))
+	for _, pcs := range cur {
+		delta := PcStatusDelta{PcStatus: pcs}
+		key := fileKey(pcs.MountNs, pcs.Name)
+		old, ok := last[key]
+		switch {
+		case ok:
+			delta.Delta = pcs.Cached - old.Cached
+			delta.PercentDelta = pcs.Percent - old.Percent
+			elapsed := pcs.Timestamp.Sub(old.Timestamp).Seconds()
+			if elapsed > 0 {
+				delta.Rate = float64(int64(delta.Delta)*pageSize) / elapsed
+			}
+			delete(last, key)
+		case prev != nil:
+			delta.Delta = pcs.Cached
+			delta.PercentDelta = pcs.Percent
+			delta.Change = changeAppeared
+		}
+		deltas = append(deltas, delta)
+	}
+
+	// keep the order of prev for the disappeared files.
+	for _, old := range prev {
+		if _, ok := last[fileKey(old.MountNs, old.Name)]; !ok {
+			continue
+		}
+
+		delta := PcStatusDelta{PcStatus: old, Change: changeDisappeared}
+		delta.Cached = 0
+		delta.Uncached = old.Pages
+		delta.Percent = 0
+		delta.Delta = -old.Cached
+		delta.PercentDelta = -old.Percent
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+func (stats PcStatusDeltaList) FormatText() {
+	stats.formatTable(textTable)
+}
+
+func (stats PcStatusDeltaList) FormatUnicode() {
+	stats.formatTable(unicodeTable)
+}
+
+func (stats PcStatusDeltaList) FormatPlain() {
+	stats.formatTable(plainTable)
+}
+
+func (stats PcStatusDeltaList) formatTable(style tableStyle) {
+	header := []string{"Name", "Size", "Cached Size", "Cached Pages", "Delta Pages", "Delta Percent", "Rate", "Percent"}
+	row := func(name string, size, cachedSize int64, cached, delta int, percentDelta, rate, percent float64) []string {
+		return []string{
+			name,
+			ConvertUnit(size),
+			ConvertUnit(cachedSize),
+			strconv.Itoa(cached),
+			fmt.Sprintf("%+d", delta),
+			fmt.Sprintf("%+.3f", percentDelta),
+			signedRate(rate),
+			fmt.Sprintf("%.3f", percent),
+		}
+	}
+
+	var size_sum, page_sum, cached_page_sum, cached_size_sum int64
+	var delta_sum int
+	var rate_sum float64
+
+	rows := make([][]string, 0, len(stats))
+	for _, pcs := range stats {
+		cached_size := cachedSize(pcs.PcStatus)
+		rows = append(rows, row(pcs.displayName(), pcs.Size, cached_size, pcs.Cached, pcs.Delta, pcs.PercentDelta, pcs.Rate, pcs.Percent))
+
+		size_sum += pcs.Size
+		page_sum += int64(pcs.Pages)
+		cached_page_sum += int64(pcs.Cached)
+		cached_size_sum += cached_size
+		delta_sum += pcs.Delta
+		rate_sum += pcs.Rate
+	}
+
+	var percent, percent_delta float64
+	if page_sum > 0 {
+		percent = float64(cached_page_sum) / float64(page_sum) * 100.00
+		percent_delta = float64(delta_sum) / float64(page_sum) * 100.00
+	}
+
+	sum := row("Sum", size_sum, cached_size_sum, int(cached_page_sum), delta_sum, percent_delta, rate_sum, percent)
+	printTable(style, header, rows, sum)
+}
+
+func (stats PcStatusDeltaList) FormatTerse() {
+	fmt.Println("name,size,timestamp,mtime,pages,cached,percent,delta,percent_delta,rate,change")
+	for _, pcs := range stats {
+		fmt.Printf("%s,%d,%d,%d,%d,%d,%g,%d,%g,%g,%s\n",
+			pcs.Name, pcs.Size, pcs.Timestamp.Unix(), pcs.Mtime.Unix(), pcs.Pages, pcs.Cached, pcs.Percent, pcs.Delta, pcs.PercentDelta, pcs.Rate, pcs.Change)
+	}
+}
+
+func (stats PcStatusDeltaList) FormatJson() {
+	writeJSON(stats, false)
+}
+
+func (stats PcStatusDeltaList) FormatNDJson() {
+	writeJSON(stats, true)
+}
+
+// displayName is the name of the file with its change, if any.
+func (pcs PcStatusDelta) displayName() string {
+	if pcs.Change == "" {
+		return pcs.Name
+	}
+	return fmt.Sprintf("%s (%s)", pcs.Name, pcs.Change)
+}
+
+// signedRate formats bytes per second with an explicit sign.
+func signedRate(rate float64) string {
+	return signedUnit(int64(rate)) + "/s"
+}
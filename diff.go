@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// handleDiff compares two reports saved with -json or -ndjson and prints
+// the change of each file, the largest changes first.
+func (pg *pgcacher) handleDiff(oldReport, newReport string) {
+	prev, err := loadReport(oldReport)
+	if err != nil {
+		log.Fatalf("failed to load report %q, err: %v", oldReport, err)
+	}
+	cur, err := loadReport(newReport)
+	if err != nil {
+		log.Fatalf("failed to load report %q, err: %v", newReport, err)
+	}
+
+	deltas := newPcStatusDeltaList(prev, cur)
+	sort.SliceStable(deltas, func(i, j int) bool {
+		return abs(deltas[i].Delta) > abs(deltas[j].Delta)
+	})
+
+	pg.outputDelta(deltas, pg.option.limit)
+}
+
+// loadReport reads the stats from a report. a report is a JSON array of
+// stats, or one stats per line. when a report holds several arrays, such
+// as the output of -interval, the last one wins.
+func loadReport(fname string) (PcStatusList, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := PcStatusList{}
+	dec := json.NewDecoder(f)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(raw) > 0 && raw[0] == '[' {
+			stats = PcStatusList{}
+			if err := json.Unmarshal(raw, &stats); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var pcs PcStatusList
+		if err := json.Unmarshal(append(append([]byte{'['}, raw...), ']'), &pcs); err != nil {
+			return nil, fmt.Errorf("unexpected JSON value: %v", err)
+		}
+		stats = append(stats, pcs...)
+	}
+
+	return stats, nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
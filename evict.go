@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// handleEvict drops the selected files from the page cache, the page cache
+// stats of the files are printed before and after the eviction.
+func (pg *pgcacher) handleEvict() {
+	before := pg.getPageCacheStats()
+
+	pg.outputTitle("before evict")
+	pg.output(before, pg.option.limit)
+
+	evicted := pg.forEachFile(before, pg.option.worker, "evict", func(_ pcstats.PcStatus, fname string) error {
+		return pcstats.EvictFile(fname)
+	})
+
+	pg.setFiles(evicted)
+	after := pg.getPageCacheStats()
+
+	pg.outputTitle("after evict")
+	pg.output(after, pg.option.limit)
+}
+
+// outputTitle prints a title line between two reports, machine-readable
+// formats are left untouched.
+func (pg *pgcacher) outputTitle(title string) {
+	if pg.option.json || pg.option.ndjson || pg.option.terse {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+}
+
+// reportWriter is where the reports printed beside the stats go, stderr for
+// machine-readable formats to stay out of their way.
+func (pg *pgcacher) reportWriter() io.Writer {
+	if pg.option.json || pg.option.ndjson || pg.option.terse {
+		return os.Stderr
+	}
+	return os.Stdout
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handleExporter serves the page cache stats as prometheus metrics on the
+// listen address. the files are rescanned every interval in the background,
+// scrapes always get the result of the last scan.
+func (pg *pgcacher) handleExporter(listen string, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	var (
+		mu      sync.RWMutex
+		metrics []byte
+	)
+
+	scan := func() {
+		start := time.Now()
+
+		// the files of processes come and go.
+		// the processes may have exited, they are looked up again on the
+		// next scan.
+		if pg.scansProcesses() {
+			if err := pg.selectFiles(); err != nil {
+				log.Printf("failed to select the files, err: %v", err)
+			}
+		}
+		stats := pg.getPageCacheStats()
+
+		buf := new(bytes.Buffer)
+		writeMetrics(buf, stats, pg.procs, pg.option.limit)
+		fmt.Fprintf(buf, "# HELP pgcacher_scan_duration_seconds Time spent on the last scan.\n")
+		fmt.Fprintf(buf, "# TYPE pgcacher_scan_duration_seconds gauge\n")
+		fmt.Fprintf(buf, "pgcacher_scan_duration_seconds %g\n", time.Since(start).Seconds())
+
+		mu.Lock()
+		metrics = buf.Bytes()
+		mu.Unlock()
+	}
+
+	scan()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			scan()
+		}
+	}()
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.RLock()
+		defer mu.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(metrics)
+	})
+
+	log.Printf("serving metrics on http://%s/metrics, rescan every %s", listen, interval)
+	log.Fatal(http.ListenAndServe(listen, nil))
+}
+
+type metricAggregate struct {
+	labels string
+	size   int64
+	cached int64
+}
+
+// writeMetrics writes the stats in the prometheus text format. only the top
+// limit files, processes and dirs by cached bytes are written to cap the
+// cardinality.
+func writeMetrics(w io.Writer, stats PcStatusList, procs []processFiles, limit int) {
+	byName := make(map[string]int, len(stats))
+	for i, pcs := range stats {
+		byName[fileKey(pcs.MountNs, pcs.Name)] = i
+		for _, alias := range pcs.Aliases {
+			byName[alias] = i
+		}
+	}
+
+	// files, stats are sorted by cached pages already.
+	files := make([]metricAggregate, 0, min(len(stats), limit))
+	for _, pcs := range stats[:min(len(stats), limit)] {
+		labels := fmt.Sprintf(`file="%s"`, escapeLabel(pcs.Name))
+		if pcs.MountNs != "" {
+			labels += fmt.Sprintf(`,mount_ns="%s"`, escapeLabel(pcs.MountNs))
+		}
+		files = append(files, metricAggregate{
+			labels: labels,
+			size:   pcs.Size,
+			cached: cachedSize(pcs),
+		})
+	}
+
+	// processes, a file shared by several processes counts for each of them.
+	processes := make([]metricAggregate, 0, len(procs))
+	for _, proc := range procs {
+		agg := metricAggregate{
+			labels: fmt.Sprintf(`pid="%d",executable="%s"`, proc.pid, escapeLabel(proc.exe)),
+		}
+
+		seen := make(map[int]emptyNull, len(proc.files))
+		for _, fname := range proc.files {
+			i, ok := byName[fileKey(proc.mntns, fname)]
+			if _, dup := seen[i]; dup || !ok {
+				continue
+			}
+			seen[i] = emptyNull{}
+
+			agg.size += stats[i].Size
+			agg.cached += cachedSize(stats[i])
+		}
+		processes = append(processes, agg)
+	}
+
+	// dirs holding the files.
+	groups := groupByDir(stats, 0)
+	dirs := make([]metricAggregate, 0, len(groups))
+	for _, g := range groups {
+		dirs = append(dirs, metricAggregate{
+			labels: fmt.Sprintf(`dir="%s"`, escapeLabel(g.Dir)),
+			size:   g.Size,
+			cached: g.Bytes,
+		})
+	}
+
+	writeMetricGroup(w, "file", files, limit)
+	writeMetricGroup(w, "process", processes, limit)
+	writeMetricGroup(w, "dir", dirs, limit)
+}
+
+func writeMetricGroup(w io.Writer, kind string, aggs []metricAggregate, limit int) {
+	sort.SliceStable(aggs, func(i, j int) bool {
+		return aggs[i].cached > aggs[j].cached
+	})
+	aggs = aggs[:min(len(aggs), limit)]
+
+	name := "pgcacher_" + kind + "_cached_bytes"
+	fmt.Fprintf(w, "# HELP %s Bytes in the page cache by %s.\n", name, kind)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, agg := range aggs {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, agg.labels, agg.cached)
+	}
+
+	name = "pgcacher_" + kind + "_size_bytes"
+	fmt.Fprintf(w, "# HELP %s Total size of the files in bytes by %s.\n", name, kind)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, agg := range aggs {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, agg.labels, agg.size)
+	}
+
+	name = "pgcacher_" + kind + "_cached_percent"
+	fmt.Fprintf(w, "# HELP %s Percent of bytes in the page cache by %s.\n", name, kind)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, agg := range aggs {
+		var percent float64
+		if agg.size > 0 {
+			percent = float64(agg.cached) / float64(agg.size) * 100.00
+		}
+		fmt.Fprintf(w, "%s{%s} %g\n", name, agg.labels, percent)
+	}
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
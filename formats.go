@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
@@ -29,9 +32,10 @@ func (stats PcStatusList) FormatUnicode() {
 
 	// create horizontal grid line
 	pad := strings.Repeat("─", maxName+2)
-	top := fmt.Sprintf("┌%s┬────────────────┬─────────────┬────────────────┬─────────────┬─────────┐", pad)
-	hr := fmt.Sprintf("├%s┼────────────────┼─────────────┼────────────────┼─────────────┼─────────┤", pad)
-	bot := fmt.Sprintf("└%s┴────────────────┴─────────────┴────────────────┴─────────────┴─────────┘", pad)
+	procs := stats.maxProcessesLen()
+	top := fmt.Sprintf("┌%s┬────────────────┬─────────────┬────────────────┬─────────────┬─────────%s┐", pad, extraLine("┬", "─", procs))
+	hr := fmt.Sprintf("├%s┼────────────────┼─────────────┼────────────────┼─────────────┼─────────%s┤", pad, extraLine("┼", "─", procs))
+	bot := fmt.Sprintf("└%s┴────────────────┴─────────────┴────────────────┴─────────────┴─────────%s┘", pad, extraLine("┴", "─", procs))
 
 	var size_sum, page_sum, cached_page_sum, cached_size, cached_size_sum int64
 
@@ -39,7 +43,7 @@ func (stats PcStatusList) FormatUnicode() {
 
 	// -nohdr may be chosen to save 2 lines of precious vertical space
 	pad = strings.Repeat(" ", maxName-4)
-	fmt.Printf("│ Name%s │ Size           │ Pages       │ Cached Size    │ Cached Pages│ Percent │\n", pad)
+	fmt.Printf("│ Name%s │ Size           │ Pages       │ Cached Size    │ Cached Pages│ Percent │%s\n", pad, extraCell("Processes", procs, "│"))
 	fmt.Println(hr)
 
 	for _, pcs := range stats {
@@ -52,8 +56,8 @@ func (stats PcStatusList) FormatUnicode() {
 
 		// %-7.3f was chosen to make it easy to scan the percentages vertically
 		// I tried a few different formats only this one kept the decimals aligned
-		fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │\n",
-			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent)
+		fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │%s\n",
+			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent, extraCell(formatHolders(pcs.Processes), procs, "│"))
 
 		size_sum += pcs.Size
 		page_sum += int64(pcs.Pages)
@@ -63,8 +67,8 @@ func (stats PcStatusList) FormatUnicode() {
 
 	fmt.Println(hr)
 	pad = strings.Repeat(" ", maxName-len("Sum"))
-	fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │\n",
-		"Sum", pad, ConvertUnit(size_sum), page_sum, ConvertUnit(cached_size_sum), cached_page_sum, (float64(cached_page_sum)/float64(page_sum))*100.00)
+	fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │%s\n",
+		"Sum", pad, ConvertUnit(size_sum), page_sum, ConvertUnit(cached_size_sum), cached_page_sum, (float64(cached_page_sum)/float64(page_sum))*100.00, extraCell("", procs, "│"))
 	fmt.Println(bot)
 }
 
@@ -73,16 +77,17 @@ func (stats PcStatusList) FormatText() {
 
 	// create horizontal grid line
 	pad := strings.Repeat("-", maxName+2)
-	top := fmt.Sprintf("+%s+----------------+-------------+----------------+-------------+---------+", pad)
-	hr := fmt.Sprintf("|%s+----------------+-------------+----------------+-------------+---------|", pad)
-	bot := fmt.Sprintf("+%s+----------------+-------------+----------------+-------------+---------+", pad)
+	procs := stats.maxProcessesLen()
+	top := fmt.Sprintf("+%s+----------------+-------------+----------------+-------------+---------%s+", pad, extraLine("+", "-", procs))
+	hr := fmt.Sprintf("|%s+----------------+-------------+----------------+-------------+---------%s|", pad, extraLine("+", "-", procs))
+	bot := fmt.Sprintf("+%s+----------------+-------------+----------------+-------------+---------%s+", pad, extraLine("+", "-", procs))
 	var size_sum, page_sum, cached_page_sum, cached_size, cached_size_sum int64
 
 	fmt.Println(top)
 
 	// -nohdr may be chosen to save 2 lines of precious vertical space
 	pad = strings.Repeat(" ", maxName-4)
-	fmt.Printf("| Name%s | Size           │ Pages       │ Cached Size    │ Cached Pages│ Percent │\n", pad)
+	fmt.Printf("| Name%s | Size           │ Pages       │ Cached Size    │ Cached Pages│ Percent │%s\n", pad, extraCell("Processes", procs, "|"))
 	fmt.Println(hr)
 
 	for _, pcs := range stats {
@@ -91,8 +96,8 @@ func (stats PcStatusList) FormatText() {
 
 		// %-7.3f was chosen to make it easy to scan the percentages vertically
 		// I tried a few different formats only this one kept the decimals aligned
-		fmt.Printf("| %s%s | %-15s| %-12d| %-15s| %-12d| %-7.3f |\n",
-			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent)
+		fmt.Printf("| %s%s | %-15s| %-12d| %-15s| %-12d| %-7.3f |%s\n",
+			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent, extraCell(formatHolders(pcs.Processes), procs, "|"))
 
 		size_sum += pcs.Size
 		page_sum += int64(pcs.Pages)
@@ -102,8 +107,8 @@ func (stats PcStatusList) FormatText() {
 
 	fmt.Println(hr)
 	pad = strings.Repeat(" ", maxName-len("Sum"))
-	fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │\n",
-		"Sum", pad, ConvertUnit(size_sum), page_sum, ConvertUnit(cached_size_sum), cached_page_sum, (float64(cached_page_sum)/float64(page_sum))*100.00)
+	fmt.Printf("│ %s%s │ %-15s│ %-12d│ %-15s│ %-12d│ %-7.3f │%s\n",
+		"Sum", pad, ConvertUnit(size_sum), page_sum, ConvertUnit(cached_size_sum), cached_page_sum, (float64(cached_page_sum)/float64(page_sum))*100.00, extraCell("", procs, "│"))
 	fmt.Println(bot)
 }
 
@@ -114,7 +119,8 @@ func (stats PcStatusList) FormatPlain() {
 
 	// -nohdr may be chosen to save 2 lines of precious vertical space
 	pad := strings.Repeat(" ", maxName-4)
-	fmt.Printf("Name%s  Size            Pages        Cached Size     Cached Pages Percent\n", pad)
+	procs := stats.maxProcessesLen()
+	fmt.Printf("Name%s  Size            Pages        Cached Size     Cached Pages Percent%s\n", pad, extraCell("Processes", procs, ""))
 
 	for _, pcs := range stats {
 		pad := strings.Repeat(" ", maxName-len(pcs.Name))
@@ -122,8 +128,8 @@ func (stats PcStatusList) FormatPlain() {
 
 		// %-7.3f was chosen to make it easy to scan the percentages vertically
 		// I tried a few different formats only this one kept the decimals aligned
-		fmt.Printf("%s%s  %-15s %-12d %-15s %-12d %-7.3f\n",
-			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent)
+		fmt.Printf("%s%s  %-15s %-12d %-15s %-12d %-7.3f%s\n",
+			pcs.Name, pad, ConvertUnit(pcs.Size), pcs.Pages, ConvertUnit(cached_size), pcs.Cached, pcs.Percent, extraCell(formatHolders(pcs.Processes), procs, ""))
 
 		size_sum += pcs.Size
 		page_sum += int64(pcs.Pages)
@@ -136,23 +142,201 @@ func (stats PcStatusList) FormatPlain() {
 		"Sum", pad, ConvertUnit(size_sum), page_sum, ConvertUnit(cached_size_sum), cached_page_sum, (float64(cached_page_sum)/float64(page_sum))*100.00)
 }
 
+// histoWidth is the number of cells of the residency strip of each file.
+const histoWidth = 50
+
+// histoBlocks are the unicode block characters for the cached fraction of
+// a cell, from nothing cached to fully cached.
+var histoBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+func (stats PcStatusList) FormatHisto() {
+	maxName := stats.maxNameLen()
+
+	pad := strings.Repeat(" ", maxName-4)
+	fmt.Printf("Name%s  %-*s  Cached Size     Percent\n", pad, histoWidth+2, "Residency")
+
+	for _, pcs := range stats {
+		pad := strings.Repeat(" ", maxName-len(pcs.Name))
+		cached_size := int64(float64(pcs.Size) * pcs.Percent / 100)
+
+		fmt.Printf("%s%s  │%s│  %-15s %-7.3f\n",
+			pcs.Name, pad, histoStrip(pcs, histoWidth), ConvertUnit(cached_size), pcs.Percent)
+	}
+}
+
+// histoStrip renders the cached ranges of the file as a bar of width cells,
+// each cell shows how much of its slice of the file is cached.
+func histoStrip(pcs pcstats.PcStatus, width int) string {
+	var sb strings.Builder
+	for _, frac := range histoCells(pcs.CachedRanges, int64(pcs.Pages), width) {
+		idx := int(math.Ceil(frac * float64(len(histoBlocks)-1)))
+		sb.WriteRune(histoBlocks[idx])
+	}
+	return sb.String()
+}
+
+// histoCells splits pages into width slices and returns the cached
+// fraction of each slice. a slice of a small file may be a single page
+// shared with its neighbour.
+func histoCells(ranges []pcstats.PageRange, pages int64, width int) []float64 {
+	cells := make([]float64, width)
+	if pages == 0 {
+		return cells
+	}
+
+	for i := range cells {
+		start := int64(i) * pages / int64(width)
+		end := int64(i+1) * pages / int64(width)
+		if end == start {
+			end = start + 1
+		}
+
+		var cached int64
+		for _, r := range ranges {
+			lo, hi := r.Start, r.End
+			if lo < start {
+				lo = start
+			}
+			if hi > end {
+				hi = end
+			}
+			if hi > lo {
+				cached += hi - lo
+			}
+		}
+		cells[i] = float64(cached) / float64(end-start)
+	}
+
+	return cells
+}
+
 func (stats PcStatusList) FormatTerse() {
-	fmt.Println("name,size,timestamp,mtime,pages,cached,percent")
+	procs := stats.maxProcessesLen() > 0
+	aliases := stats.hasAliases()
+	header := "name,size,timestamp,mtime,pages,cached,percent"
+	if procs {
+		header += ",processes"
+	}
+	if aliases {
+		header += ",aliases"
+	}
+	fmt.Println(header)
 	for _, pcs := range stats {
 		time := pcs.Timestamp.Unix()
 		mtime := pcs.Mtime.Unix()
-		fmt.Printf("%s,%d,%d,%d,%d,%d,%g\n",
+		fmt.Printf("%s,%d,%d,%d,%d,%d,%g",
 			pcs.Name, pcs.Size, time, mtime, pcs.Pages, pcs.Cached, pcs.Percent)
+		if procs {
+			pids := make([]string, 0, len(pcs.Processes))
+			for _, holder := range pcs.Processes {
+				pids = append(pids, strconv.Itoa(holder.Pid))
+			}
+			fmt.Printf(",%s", strings.Join(pids, ";"))
+		}
+		if aliases {
+			fmt.Printf(",%s", strings.Join(pcs.Aliases, ";"))
+		}
+		fmt.Println()
+	}
+}
+
+func (stats PcStatusList) hasAliases() bool {
+	for _, pcs := range stats {
+		if len(pcs.Aliases) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasesNote tells how many other names the file has, for the tables.
+func aliasesNote(aliases []string) string {
+	switch len(aliases) {
+	case 0:
+		return ""
+	case 1:
+		return " (+1 alias)"
+	default:
+		return fmt.Sprintf(" (+%d aliases)", len(aliases))
 	}
 }
 
 func (stats PcStatusList) FormatJson() {
-	b, err := json.Marshal(stats)
-	if err != nil {
-		log.Fatalf("JSON formatting failed: %s\n", err)
+	writeJSON(stats, false)
+}
+
+func (stats PcStatusList) FormatNDJson() {
+	writeJSON(stats, true)
+}
+
+// writeJSON prints the rows, a slice, as a json array, or as one json
+// object per line with ndjson.
+func writeJSON(rows interface{}, ndjson bool) {
+	values := []interface{}{rows}
+	if ndjson {
+		v := reflect.ValueOf(rows)
+		values = make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).Interface()
+		}
+	}
+
+	for _, value := range values {
+		b, err := json.Marshal(value)
+		if err != nil {
+			log.Fatalf("JSON formatting failed: %s\n", err)
+		}
+		os.Stdout.Write(b)
+		fmt.Println("")
+	}
+}
+
+// maxProcessesLen returns the width of the processes column, 0 when no
+// file has processes and the column is not printed.
+func (stats PcStatusList) maxProcessesLen() int {
+	var maxLen int
+	for _, pcs := range stats {
+		if l := len(formatHolders(pcs.Processes)); l > maxLen {
+			maxLen = l
+		}
+	}
+
+	if maxLen > 0 && maxLen < len("Processes") {
+		maxLen = len("Processes")
+	}
+	return maxLen
+}
+
+// extraLine returns the horizontal line of an optional column.
+func extraLine(sep, fill string, width int) string {
+	if width == 0 {
+		return ""
+	}
+	return sep + strings.Repeat(fill, width+2)
+}
+
+// extraCell returns the cell of an optional column.
+func extraCell(s string, width int, bar string) string {
+	if width == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" %-*s %s", width, s, bar)
+}
+
+// maxHolders is the number of processes shown for a file, files such as
+// libc are mapped by nearly every process.
+const maxHolders = 3
+
+func formatHolders(holders []pcstats.Holder) string {
+	names := make([]string, 0, maxHolders+1)
+	for i, holder := range holders {
+		if i == maxHolders {
+			names = append(names, fmt.Sprintf("+%d more", len(holders)-maxHolders))
+			break
+		}
+		names = append(names, fmt.Sprintf("%s(%d)", holder.Executable, holder.Pid))
 	}
-	os.Stdout.Write(b)
-	fmt.Println("")
+	return strings.Join(names, ", ")
 }
 
 // maxNameLen returns the len of longest filename in the stat list
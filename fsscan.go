@@ -0,0 +1,246 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// fsScanSummary is the totals of all files scanned, not only the top ones.
+type fsScanSummary struct {
+	roots  []string
+	files  int64
+	size   int64
+	cached int64
+}
+
+// topStats keeps the limit files with the most cached pages, it's a min
+// heap so the least cached one is dropped first. a file reached through
+// several names, e.g. hardlinks and bind mounts, takes a single slot.
+type topStats struct {
+	limit int
+	stats PcStatusList
+	index map[fileID]int // position of the files with an inode in stats
+}
+
+func (t *topStats) Len() int           { return len(t.stats) }
+func (t *topStats) Less(i, j int) bool { return t.stats[i].Cached < t.stats[j].Cached }
+
+func (t *topStats) Swap(i, j int) {
+	t.stats[i], t.stats[j] = t.stats[j], t.stats[i]
+	t.setIndex(i)
+	t.setIndex(j)
+}
+
+func (t *topStats) Push(x interface{}) {
+	t.stats = append(t.stats, x.(pcstats.PcStatus))
+	t.setIndex(len(t.stats) - 1)
+}
+
+func (t *topStats) Pop() interface{} {
+	last := t.stats[len(t.stats)-1]
+	t.stats = t.stats[:len(t.stats)-1]
+	delete(t.index, fileID{last.Dev, last.Inode})
+	return last
+}
+
+func (t *topStats) setIndex(i int) {
+	if t.stats[i].Inode == 0 {
+		return
+	}
+	if t.index == nil {
+		t.index = make(map[fileID]int)
+	}
+	t.index[fileID{t.stats[i].Dev, t.stats[i].Inode}] = i
+}
+
+// add keeps the stats when it's among the top limit ones, another name of
+// a file kept already is merged into it as an alias.
+func (t *topStats) add(pcs pcstats.PcStatus) {
+	if t.limit <= 0 {
+		return
+	}
+	if i, ok := t.index[fileID{pcs.Dev, pcs.Inode}]; ok && pcs.Inode != 0 {
+		t.stats[i] = dedupFiles(PcStatusList{t.stats[i], pcs})[0]
+		heap.Fix(t, i)
+		return
+	}
+	if len(t.stats) < t.limit {
+		heap.Push(t, pcs)
+		return
+	}
+	if pcs.Cached > t.stats[0].Cached {
+		delete(t.index, fileID{t.stats[0].Dev, t.stats[0].Inode})
+		t.stats[0] = pcs
+		t.setIndex(0)
+		heap.Fix(t, 0)
+	}
+}
+
+// handleFsScan scans every regular file under the mount points, or of the
+// mounts of the filesystem types, staying on their filesystems. files are
+// measured while the dirs are walked and only the top limit files are kept,
+// so the memory stays flat whatever the number of files. only the ids of
+// the files with several links are kept to count them once in the totals.
+func (pg *pgcacher) handleFsScan(mounts, fstypes string) {
+	roots, err := scanRoots(mounts, fstypes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(roots) == 0 {
+		log.Fatalf("no filesystem to scan")
+	}
+
+	var (
+		mu      sync.Mutex
+		top     = &topStats{limit: pg.option.limit}
+		summary fsScanSummary
+		seen    = make(map[fileID]emptyNull)
+		queue   = make(chan string, 1024)
+	)
+
+	go func() {
+		walker := newDirWalker(pg.option.worker, 0, false, true, pg.ignoreFile)
+		walker.walk(roots, queue)
+		close(queue)
+	}()
+
+	pg.measureQueue(queue, pg.option.worker, "", nil, nil, func(status pcstats.PcStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		top.add(status)
+
+		// the roots don't overlap, only the hardlinks of a file reach it
+		// again.
+		if status.Nlink > 1 {
+			id := fileID{status.Dev, status.Inode}
+			if _, ok := seen[id]; ok {
+				return
+			}
+			seen[id] = emptyNull{}
+		}
+		summary.files++
+		summary.size += status.Size
+		summary.cached += cachedSize(status)
+	})
+
+	summary.roots = roots
+
+	stats := dedupFiles(top.stats)
+	sort.Sort(stats)
+	pg.output(stats, pg.option.limit)
+	pg.outputFsScanSummary(summary)
+}
+
+// scanRoot is a dir to scan, with the device and the path in the filesystem
+// of the dir to tell the nested roots apart.
+type scanRoot struct {
+	dir          string
+	major, minor uint32
+	fsPath       string // the path of the dir in its filesystem, "" when unknown
+}
+
+// scanRoots resolves the mount points and the mounts of the filesystem
+// types, both are separated by commas. pseudo filesystems are refused.
+func scanRoots(mounts, fstypes string) ([]string, error) {
+	all, err := pcstats.ReadMounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts, err: %v", err)
+	}
+
+	var roots []scanRoot
+	for _, dir := range splitList(mounts) {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if dir, err = filepath.EvalSymlinks(dir); err != nil {
+			return nil, err
+		}
+
+		m, ok := pcstats.FindMount(all, dir)
+		if !ok {
+			roots = append(roots, scanRoot{dir: dir})
+			continue
+		}
+		if pcstats.IsPseudoFS(m.FSType) {
+			return nil, fmt.Errorf("%s is on the pseudo filesystem %s", dir, m.FSType)
+		}
+		rel, err := filepath.Rel(m.MountPoint, dir)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, scanRoot{dir: dir, major: m.Major, minor: m.Minor, fsPath: filepath.Join(m.Root, rel)})
+	}
+
+	types := make(map[string]bool)
+	for _, fstype := range splitList(fstypes) {
+		types[fstype] = true
+	}
+	for _, m := range all {
+		if !types[m.FSType] || pcstats.IsPseudoFS(m.FSType) {
+			continue
+		}
+		roots = append(roots, scanRoot{dir: m.MountPoint, major: m.Major, minor: m.Minor, fsPath: m.Root})
+	}
+
+	return dedupRoots(roots), nil
+}
+
+// dedupRoots drops the roots inside of another root of the same filesystem,
+// e.g. a filesystem mounted several times with bind mounts, or the mount
+// points given twice. the first of the same roots is kept.
+func dedupRoots(roots []scanRoot) []string {
+	inside := func(r, of scanRoot) bool {
+		if r.fsPath == "" || of.fsPath == "" || r.major != of.major || r.minor != of.minor {
+			return false
+		}
+		return of.fsPath == "/" || r.fsPath == of.fsPath || strings.HasPrefix(r.fsPath, of.fsPath+"/")
+	}
+
+	var dirs []string
+	for i, r := range roots {
+		covered := false
+		for j, of := range roots {
+			if i == j || !inside(r, of) {
+				continue
+			}
+			// of the same roots, the first one is kept.
+			if r.fsPath != of.fsPath || j < i {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			dirs = append(dirs, r.dir)
+		}
+	}
+	return dirs
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// outputFsScanSummary prints the totals of the scan.
+func (pg *pgcacher) outputFsScanSummary(summary fsScanSummary) {
+	out := pg.reportWriter()
+
+	fmt.Fprintf(out, "scanned %d files of %s in %s, %s cached, %.3f%%\n",
+		summary.files, humanize.IBytes(uint64(summary.size)), strings.Join(summary.roots, ", "),
+		humanize.IBytes(uint64(summary.cached)), percentOf(summary.cached, summary.size))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GroupStat is the sum of the page cache stats of the files under a dir.
+type GroupStat struct {
+	Dir     string  `json:"dir"`
+	Files   int     `json:"files"`
+	Size    int64   `json:"size"`
+	Pages   int64   `json:"pages"`
+	Cached  int64   `json:"cached"`      // cached pages
+	Bytes   int64   `json:"cached_size"` // cached bytes
+	Percent float64 `json:"percent"`
+
+	stats PcStatusList // files under the dir, sorted by cached pages
+}
+
+type GroupStatList []GroupStat
+
+// parseGroupBy parses the -group-by option, 'dir' groups files by their
+// parent dir and 'dir:N' by the first N components of their path. 'mount'
+// and 'fstype' have no depth.
+func parseGroupBy(s string) (int, error) {
+	if s == groupByMount || s == groupByFSType {
+		return 0, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	if parts[0] != "dir" {
+		return 0, fmt.Errorf("unknown group-by %q, should be dir, dir:depth, mount or fstype", s)
+	}
+	if len(parts) == 1 {
+		return 0, nil
+	}
+
+	depth, err := strconv.Atoi(parts[1])
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid depth in group-by %q", s)
+	}
+	return depth, nil
+}
+
+// groupDir returns the dir of the file at the depth, the parent dir when
+// depth is 0 or deeper than the file.
+func groupDir(fname string, depth int) string {
+	dir := path.Dir(fname)
+	if depth <= 0 {
+		return dir
+	}
+
+	abs := strings.HasPrefix(dir, "/")
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+
+	dir = strings.Join(parts, "/")
+	if abs {
+		dir = "/" + dir
+	}
+	return dir
+}
+
+// groupByDir rolls the stats up into dirs, sorted by cached pages.
+func groupByDir(stats PcStatusList, depth int) GroupStatList {
+	index := make(map[string]int)
+	groups := make(GroupStatList, 0)
+	for _, pcs := range stats {
+		dir := groupDir(pcs.Name, depth)
+		i, ok := index[dir]
+		if !ok {
+			i = len(groups)
+			index[dir] = i
+			groups = append(groups, GroupStat{Dir: dir})
+		}
+
+		g := &groups[i]
+		g.Files++
+		g.Size += pcs.Size
+		g.Pages += int64(pcs.Pages)
+		g.Cached += int64(pcs.Cached)
+		g.Bytes += cachedSize(pcs)
+		g.stats = append(g.stats, pcs)
+	}
+
+	for i := range groups {
+		groups[i].Percent = percentOf(groups[i].Cached, groups[i].Pages)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Cached > groups[j].Cached
+	})
+	return groups
+}
+
+func (stats GroupStatList) FormatText() {
+	stats.formatTable(textTable)
+}
+
+func (stats GroupStatList) FormatUnicode() {
+	stats.formatTable(unicodeTable)
+}
+
+func (stats GroupStatList) FormatPlain() {
+	stats.formatTable(plainTable)
+}
+
+func (stats GroupStatList) formatTable(style tableStyle) {
+	header := []string{"Dir", "Files", "Size", "Pages", "Cached Size", "Cached Pages", "Percent"}
+	row := func(dir string, files int, size, pages, bytes, cached int64) []string {
+		return []string{
+			dir,
+			strconv.Itoa(files),
+			ConvertUnit(size),
+			strconv.FormatInt(pages, 10),
+			ConvertUnit(bytes),
+			strconv.FormatInt(cached, 10),
+			fmt.Sprintf("%.3f", percentOf(cached, pages)),
+		}
+	}
+
+	var sum GroupStat
+	rows := make([][]string, 0, len(stats))
+	for _, g := range stats {
+		rows = append(rows, row(g.Dir, g.Files, g.Size, g.Pages, g.Bytes, g.Cached))
+
+		sum.Files += g.Files
+		sum.Size += g.Size
+		sum.Pages += g.Pages
+		sum.Bytes += g.Bytes
+		sum.Cached += g.Cached
+	}
+
+	printTable(style, header, rows, row("Sum", sum.Files, sum.Size, sum.Pages, sum.Bytes, sum.Cached))
+}
+
+func (stats GroupStatList) FormatTerse() {
+	fmt.Println("dir,files,size,pages,cached,percent")
+	for _, g := range stats {
+		fmt.Printf("%s,%d,%d,%d,%d,%g\n", g.Dir, g.Files, g.Size, g.Pages, g.Cached, g.Percent)
+	}
+}
+
+func (stats GroupStatList) FormatJson() {
+	writeJSON(stats, false)
+}
+
+func (stats GroupStatList) FormatNDJson() {
+	writeJSON(stats, true)
+}
+
+// outputGroups prints the dirs, then the files of the top drill dirs.
+func (pg *pgcacher) outputGroups(groups GroupStatList, limit, drill int) {
+	groups = groups[:min(len(groups), limit)]
+
+	pg.format(groups)
+
+	for _, g := range groups[:min(len(groups), drill)] {
+		pg.outputTitle(g.Dir)
+		pg.output(g.stats, limit)
+	}
+}
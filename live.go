@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+type liveSort int
+
+const (
+	sortByCached liveSort = iota
+	sortByPercent
+	sortBySize
+	sortByGrowth
+)
+
+var liveSortNames = map[liveSort]string{
+	sortByCached:  "cached",
+	sortByPercent: "percent",
+	sortBySize:    "size",
+	sortByGrowth:  "growth",
+}
+
+type liveRow struct {
+	pcstats.PcStatus
+
+	cachedSize int64
+	delta      int64 // cached bytes gained since the last refresh, negative when lost
+}
+
+// liveView keeps the state of the full screen view between refreshes.
+type liveView struct {
+	rows    []liveRow
+	prev    map[string]int64
+	updated time.Time
+
+	sortBy  liveSort
+	filter  string
+	editing bool   // reading the filter from the keyboard
+	input   string // the filter being typed
+
+	display func(pcstats.PcStatus) pcstats.PcStatus // how the names are shown
+}
+
+func newLiveView(display func(pcstats.PcStatus) pcstats.PcStatus) *liveView {
+	return &liveView{display: display}
+}
+
+// update replaces the rows with the new stats, the growth of each file is
+// computed against the previous stats.
+func (v *liveView) update(stats PcStatusList) {
+	rows := make([]liveRow, 0, len(stats))
+	cur := make(map[string]int64, len(stats))
+	for _, pcs := range stats {
+		row := liveRow{PcStatus: pcs, cachedSize: cachedSize(pcs)}
+		key := fileKey(pcs.MountNs, pcs.Name)
+		if last, ok := v.prev[key]; ok {
+			row.delta = row.cachedSize - last
+		}
+
+		cur[key] = row.cachedSize
+		rows = append(rows, row)
+	}
+
+	v.rows = rows
+	v.prev = cur
+	v.updated = time.Now()
+}
+
+// visibleRows returns the filtered rows in the chosen order.
+func (v *liveView) visibleRows() []liveRow {
+	rows := make([]liveRow, 0, len(v.rows))
+	for _, row := range v.rows {
+		if v.filter != "" && !wildcardMatch(row.Name, v.filter) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		switch v.sortBy {
+		case sortByPercent:
+			return rows[i].Percent > rows[j].Percent
+		case sortBySize:
+			return rows[i].Size > rows[j].Size
+		case sortByGrowth:
+			return rows[i].delta > rows[j].delta
+		default:
+			return rows[i].cachedSize > rows[j].cachedSize
+		}
+	})
+	return rows
+}
+
+// handleKey applies a key press, it returns true when the user quits.
+func (v *liveView) handleKey(b byte) bool {
+	if v.editing {
+		switch b {
+		case '\r', '\n':
+			v.filter = v.input
+			v.editing = false
+		case 27: // esc
+			v.editing = false
+		case 127, 8: // backspace
+			if len(v.input) > 0 {
+				v.input = v.input[:len(v.input)-1]
+			}
+		default:
+			if b >= ' ' {
+				v.input += string(b)
+			}
+		}
+		return false
+	}
+
+	switch b {
+	case 'q', 3: // ctrl-c
+		return true
+	case 'c':
+		v.sortBy = sortByCached
+	case 'p':
+		v.sortBy = sortByPercent
+	case 's':
+		v.sortBy = sortBySize
+	case 'g':
+		v.sortBy = sortByGrowth
+	case '/':
+		v.editing = true
+		v.input = v.filter
+	}
+	return false
+}
+
+// render draws the whole screen, cols and lines are the terminal size.
+func (v *liveView) render(w io.Writer, cols, lines int, interval time.Duration) {
+	const fixed = 2 + 12 + 1 + 12 + 1 + 8 + 1 + 12
+	nameWidth := cols - fixed
+	if nameWidth < 10 {
+		nameWidth = 10
+	}
+
+	rows := v.visibleRows()
+
+	var size, cached, delta int64
+	for _, row := range rows {
+		size += row.Size
+		cached += row.cachedSize
+		delta += row.delta
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&sb, "pgcacher - %s, refresh every %s, sort by %s, filter '%s'\n",
+		v.updated.Format("15:04:05"), interval, liveSortNames[v.sortBy], v.filter)
+	fmt.Fprintf(&sb, "files: %d, size: %s, cached: %s, growth: %s\n",
+		len(rows), ConvertUnit(size), ConvertUnit(cached), signedUnit(delta))
+	if v.editing {
+		fmt.Fprintf(&sb, "filter: %s_\n", v.input)
+	} else {
+		sb.WriteString("keys: c/p/s/g sort by cached/percent/size/growth, / filter, q quit\n")
+	}
+	fmt.Fprintf(&sb, "\033[7m%-*s  %-12s %-12s %-8s %-12s\033[0m\n", nameWidth, "Name", "Size", "Cached", "Percent", "Growth")
+
+	// leave room for the header lines.
+	for i, row := range rows {
+		if i >= lines-5 {
+			break
+		}
+
+		name := v.display(row.PcStatus).Name
+		fmt.Fprintf(&sb, "%-*s  %-12s %-12s %-8.3f %-12s\n",
+			nameWidth, truncateLeft(name, nameWidth), ConvertUnit(row.Size), ConvertUnit(row.cachedSize), row.Percent, signedUnit(row.delta))
+	}
+
+	io.WriteString(w, sb.String())
+}
+
+// handleLive shows the stats in a full screen view and refreshes them every
+// interval until the user quits.
+func (pg *pgcacher) handleLive(interval time.Duration) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	fd := int(os.Stdin.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		log.Fatalf("failed to set the terminal into raw mode, err: %v", err)
+	}
+	defer restore()
+
+	// switch to the alternate screen and hide the cursor.
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	// logs would garble the screen.
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// the reader quits on the first key after the view is closed, it can't
+	// be woken up from the read.
+	keys := make(chan byte)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			if n != 1 {
+				continue
+			}
+			select {
+			case keys <- buf[0]:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	view := newLiveView(pg.displayStatus)
+	sample := func() {
+		// the files of processes come and go.
+		// the processes may have exited, they are looked up again on the
+		// next sample.
+		if pg.scansProcesses() {
+			if err := pg.selectFiles(); err != nil {
+				log.Printf("failed to select the files, err: %v", err)
+			}
+		}
+		view.update(pg.getPageCacheStats())
+	}
+	draw := func() {
+		cols, lines, err := terminalSize(fd)
+		if err != nil {
+			cols, lines = 120, 40
+		}
+		view.render(os.Stdout, cols, lines, interval)
+	}
+
+	sample()
+	draw()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sample()
+			draw()
+		case b, ok := <-keys:
+			if !ok || view.handleKey(b) {
+				return
+			}
+			draw()
+		case <-sigs:
+			return
+		}
+	}
+}
+
+// cachedSize estimates the cached bytes of the file by its size and the
+// percent of cached pages, same as the formatters.
+func cachedSize(pcs pcstats.PcStatus) int64 {
+	return int64(float64(pcs.Size) * pcs.Percent / 100)
+}
+
+// signedUnit is ConvertUnit with an explicit sign.
+func signedUnit(n int64) string {
+	if n < 0 {
+		return "-" + ConvertUnit(-n)
+	}
+	return "+" + ConvertUnit(n)
+}
+
+// truncateLeft keeps the tail of s, which is the most telling part of
+// a path.
+func truncateLeft(s string, width int) string {
+	if len(s) <= width {
+		return s
+	}
+	if width <= 3 {
+		return s[len(s)-width:]
+	}
+	return "..." + s[len(s)-width+3:]
+}
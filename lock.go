@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// handleLock locks the selected files into memory, the hottest files first
+// until the budget is used up, then holds them until it is signalled.
+func (pg *pgcacher) handleLock(budget int64) {
+	stats := pg.getPageCacheStats()
+
+	var (
+		pageSize = int64(os.Getpagesize())
+		used     int64
+		selected = make(PcStatusList, 0, len(stats))
+		ranges   = make(map[string][]pcstats.PageRange, len(stats))
+		mu       = sync.Mutex{}
+		locked   = make([]*pcstats.LockedFile, 0, len(stats))
+	)
+
+	// pick the files in the budget first, they are locked concurrently.
+	var budgeted int64
+	for _, status := range stats {
+		var rs []pcstats.PageRange
+		if pg.option.lockCached {
+			if len(status.CachedRanges) == 0 {
+				continue
+			}
+			rs = status.CachedRanges
+		}
+
+		length := pcstats.LockLength(status.Size, rs, pageSize)
+		if budget > 0 && budgeted+length > budget {
+			log.Printf("skipping %q: %s exceeds the lock limit", status.Name, humanize.IBytes(uint64(length)))
+			continue
+		}
+
+		budgeted += length
+		selected = append(selected, status)
+		ranges[fileKey(status.MountNs, status.Name)] = rs
+	}
+
+	done := pg.forEachFile(selected, pg.option.worker, "lock", func(status pcstats.PcStatus, fname string) error {
+		lf, err := pcstats.LockFile(fname, ranges[fileKey(status.MountNs, status.Name)])
+		if err != nil {
+			return err
+		}
+		lf.Name = fileKey(status.MountNs, status.Name)
+
+		mu.Lock()
+		used += lf.Locked
+		locked = append(locked, lf)
+		mu.Unlock()
+		return nil
+	})
+
+	pg.setFiles(done)
+	pg.output(pg.getPageCacheStats(), pg.option.limit)
+
+	log.Printf("locked %d files, %s in memory, waiting for SIGINT or SIGTERM to unlock",
+		len(locked), humanize.IBytes(uint64(used)))
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+
+	for _, lf := range locked {
+		if err := lf.Unlock(); err != nil {
+			log.Printf("failed to unlock %q: %v", lf.Name, err)
+		}
+	}
+}
@@ -6,37 +6,84 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
 	pcstat "github.com/tobert/pcstat/pkg"
 )
 
 type option struct {
-	pid, worker, depth, limit             int
-	top, terse, json, unicode             bool
-	plain, bname                          bool
+	pid, worker, depth, limit, count      int
+	drill                                 int
+	children, followSymlinks              bool
+	top, terse, json, ndjson, unicode     bool
+	evict, diff, perProcess, cgroupStat   bool
+	warm, lock, lockCached, live          bool
+	interval                              time.Duration
+	plain, bname, pps, histo              bool
 	leastSize, excludeFiles, includeFiles string
+	windowSize, warmStrategy              string
+	snapshot, restore, lockLimit, listen  string
+	groupBy, cgroup, container            string
+	processName, cmdline, user            string
+	mount, fstype                         string
 }
 
 var globalOption = new(option)
 
 func init() {
+	// the workers entering the mount namespaces of other processes lock
+	// their threads for good. the main thread must stay out of it, the mount
+	// namespace of the process is the one of its main thread.
+	runtime.LockOSThread()
+
 	// basic params
 	flag.IntVar(&globalOption.pid, "pid", 0, "show all open maps for the given pid")
+	flag.BoolVar(&globalOption.children, "children", false, "with -pid, also show the files of all descendant processes of the pid, such as the workers of postgres, nginx and gunicorn")
 	flag.IntVar(&globalOption.limit, "limit", 500, "limit the number of files displayed")
 	flag.BoolVar(&globalOption.top, "top", false, "scan the open files of all processes, show the top few files that occupy the most memory space in the page cache.")
-	flag.IntVar(&globalOption.depth, "depth", 0, "set the depth of dirs to scan")
+	flag.IntVar(&globalOption.depth, "depth", 0, "set the depth of dirs to scan, 0 means no limit")
+	flag.BoolVar(&globalOption.followSymlinks, "follow-symlinks", false, "follow the symlinks to files and dirs found in the dirs to scan, symlinks given on the command line are always followed")
+	flag.StringVar(&globalOption.cgroup, "cgroup", "", "show the files of all processes in the cgroup and its children, such as 'system.slice/docker.service', cgroup v1 and v2 are supported")
+	flag.StringVar(&globalOption.container, "container", "", "show the files of all processes of the docker, containerd, cri-o or podman container, by id, id prefix or name")
+	flag.StringVar(&globalOption.processName, "process-name", "", "show the files of all processes whose name matches the wildcards, such as 'java' and 'postgres,nginx'")
+	flag.StringVar(&globalOption.cmdline, "cmdline", "", "show the files of all processes whose command line matches the regexp, such as 'java.*kafka'")
+	flag.StringVar(&globalOption.user, "user", "", "show the files of all processes running as the user name or uid, combined with -process-name and -cmdline all of them must match")
+	flag.BoolVar(&globalOption.cgroupStat, "cgroup-stat", false, "with -cgroup, also show the file cache charged to the cgroup in memory.stat")
+	flag.StringVar(&globalOption.mount, "mount", "", "scan every regular file under the mount points, such as '/data,/var/lib/mysql', without crossing into other filesystems")
+	flag.StringVar(&globalOption.fstype, "fstype", "", "scan every regular file of all mounts of the filesystem types, such as 'ext4,xfs'. pseudo filesystems like proc, sysfs and cgroup are never scanned")
 	flag.IntVar(&globalOption.worker, "worker", 2, "concurrency workers")
 	flag.StringVar(&globalOption.leastSize, "least-size", "0mb", "ignore files smaller than the lastSize, such as 10MB and 15GB")
 	flag.StringVar(&globalOption.excludeFiles, "exclude-files", "", "exclude the specified files by wildcard, such as 'a*c?d' and '*xiaorui*,rfyiamcool'")
 	flag.StringVar(&globalOption.includeFiles, "include-files", "", "only include the specified files by wildcard, such as 'a*c?d' and '*xiaorui?cc,rfyiamcool'")
+	flag.BoolVar(&globalOption.evict, "evict", false, "drop the selected files from the page cache with posix_fadvise(DONTNEED), dirty pages are kept")
+	flag.BoolVar(&globalOption.warm, "warm", false, "load the selected files into the page cache")
+	flag.StringVar(&globalOption.warmStrategy, "warm-strategy", "willneed", "how to load files with -warm: 'willneed' uses posix_fadvise and returns before the pages are read, 'readahead' uses readahead(2), 'read' reads every page")
+	flag.StringVar(&globalOption.snapshot, "snapshot", "", "save the cached pages of the selected files to the given snapshot file")
+	flag.StringVar(&globalOption.restore, "restore", "", "load the pages recorded in the given snapshot file into the page cache, using -warm-strategy")
+	flag.BoolVar(&globalOption.lock, "lock", false, "mmap and mlock the selected files, hold them in memory until SIGINT or SIGTERM")
+	flag.BoolVar(&globalOption.lockCached, "lock-cached", false, "only lock the pages of the files that are cached now with -lock")
+	flag.StringVar(&globalOption.lockLimit, "lock-limit", "1gib", "max memory locked with -lock, the hottest files are locked first, 0 means no limit")
+	flag.StringVar(&globalOption.windowSize, "window-size", "1gib", "max length of a file mapped at once when calling mincore, such as 256MiB and 1GiB")
 
 	// show params
 	flag.BoolVar(&globalOption.terse, "terse", false, "show terse output")
 	flag.BoolVar(&globalOption.json, "json", false, "return data in JSON format")
+	flag.BoolVar(&globalOption.ndjson, "ndjson", false, "return data in newline delimited JSON format, one file per line")
+	flag.BoolVar(&globalOption.pps, "pps", false, "include the ranges of cached pages in the JSON output (can be huge!)")
 	flag.BoolVar(&globalOption.unicode, "unicode", false, "return data with unicode box characters")
 	flag.BoolVar(&globalOption.plain, "plain", false, "return data with no box characters")
+	flag.BoolVar(&globalOption.histo, "histo", false, "show the residency of each file as a strip of unicode block characters")
 	flag.BoolVar(&globalOption.bname, "bname", false, "convert paths to basename to narrow the output")
+	flag.BoolVar(&globalOption.live, "live", false, "show a full screen view like top that refreshes the stats every -interval")
+	flag.DurationVar(&globalOption.interval, "interval", 0, "the interval between two refreshes, such as 2s and 1m. without -live, print the change of each file every interval")
+	flag.BoolVar(&globalOption.perProcess, "per-process", false, "show one row per process with the size and cached bytes of its files, use with -top or -pid")
+	flag.StringVar(&globalOption.groupBy, "group-by", "", "sum the files up by dir, 'dir' groups by the parent dir and 'dir:N' by the first N components of the path, such as dir:4. 'mount' and 'fstype' sum them up by mount point and filesystem type, and compare the total with the page cache in /proc/meminfo")
+	flag.IntVar(&globalOption.drill, "drill", 0, "with -group-by, also show the files of the top N dirs")
+	flag.BoolVar(&globalOption.diff, "diff", false, "compare two reports saved with -json, such as 'pgcacher -diff before.json after.json'")
+	flag.StringVar(&globalOption.listen, "listen", "", "run as a daemon serving prometheus metrics on the address, such as ':9477', rescan every -interval, default 30s")
+	flag.IntVar(&globalOption.count, "count", 0, "the number of samples printed with -interval, 0 means forever")
 }
 
 func main() {
@@ -46,41 +93,152 @@ func main() {
 		log.Fatalf("pgcacher only support running on Linux !!!")
 	}
 	leastSize, _ := humanize.ParseBytes(globalOption.leastSize)
+	windowSize, err := humanize.ParseBytes(globalOption.windowSize)
+	if err != nil || windowSize == 0 {
+		log.Fatalf("invalid window-size %q", globalOption.windowSize)
+	}
+	pcstats.WindowSize = int64(windowSize)
+	pcstats.KeepPageRanges = globalOption.pps || globalOption.histo || globalOption.snapshot != "" ||
+		(globalOption.lock && globalOption.lockCached)
+	warmStrategy, err := pcstats.ParseWarmStrategy(globalOption.warmStrategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lockLimit, err := humanize.ParseBytes(globalOption.lockLimit)
+	if err != nil {
+		log.Fatalf("invalid lock-limit %q", globalOption.lockLimit)
+	}
+	if globalOption.cgroupStat && globalOption.cgroup == "" {
+		log.Fatalf("-cgroup-stat needs -cgroup")
+	}
+	if mode := modeFlag(); globalOption.cgroupStat && mode != "" {
+		log.Fatalf("-cgroup-stat only works with the reports, not with -%s", mode)
+	}
+	var groupDepth int
+	if globalOption.groupBy != "" {
+		if groupDepth, err = parseGroupBy(globalOption.groupBy); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if globalOption.diff {
+		if flag.NArg() != 2 {
+			log.Fatalf("-diff needs two reports, but got %d", flag.NArg())
+		}
+		pg := pgcacher{option: globalOption}
+		pg.handleDiff(flag.Arg(0), flag.Arg(1))
+		return
+	}
 
 	// running phase
-	files := flag.Args()
-	files = walkDirs(files, globalOption.depth)
+	files, dirs := splitDirs(flag.Args())
+	files, dirs = pruneCovered(files, dirs, globalOption.depth)
 
 	// init pgcacher obj
 	pg := pgcacher{
-		files:     files,
+		args:      files,
+		dirs:      dirs,
 		leastSize: int64(leastSize),
 		option:    globalOption,
 	}
 
-	if globalOption.top {
-		pg.handleTop()
-		os.Exit(0)
+	if globalOption.restore != "" {
+		pg.handleRestore(globalOption.restore, warmStrategy)
+		return
 	}
 
-	if globalOption.pid != 0 {
-		pg.appendProcessFiles(globalOption.pid)
+	if globalOption.mount != "" || globalOption.fstype != "" {
+		pg.handleFsScan(globalOption.mount, globalOption.fstype)
+		return
 	}
 
-	if len(pg.files) == 0 {
+	if err := pg.selectFiles(); err != nil {
+		log.Fatal(err)
+	}
+	if !pg.hasFiles() {
 		fmt.Println("the files is null ???")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	pg.filterFiles()
+	if globalOption.listen != "" {
+		pg.handleExporter(globalOption.listen, globalOption.interval)
+		return
+	}
+	if globalOption.live {
+		pg.handleLive(globalOption.interval)
+		return
+	}
+	if globalOption.evict {
+		pg.handleEvict()
+		return
+	}
+	if globalOption.warm {
+		pg.handleWarm(warmStrategy)
+		return
+	}
+	if globalOption.snapshot != "" {
+		pg.handleSnapshot(globalOption.snapshot)
+		return
+	}
+	if globalOption.lock {
+		pg.handleLock(int64(lockLimit))
+		return
+	}
+
+	if globalOption.interval > 0 {
+		pg.handleWatch(globalOption.interval, globalOption.count)
+		return
+	}
+
 	stats := pg.getPageCacheStats()
-	pg.output(stats, pg.option.limit)
+	if globalOption.perProcess {
+		pg.outputProcesses(newProcessStatList(stats, pg.procs), pg.option.limit)
+	} else if globalOption.groupBy == groupByMount || globalOption.groupBy == groupByFSType {
+		pg.outputMounts(stats, globalOption.groupBy, pg.option.limit, globalOption.drill)
+	} else if globalOption.groupBy != "" {
+		pg.outputGroups(groupByDir(stats, groupDepth), pg.option.limit, globalOption.drill)
+	} else {
+		pg.output(stats, pg.option.limit)
+	}
+	if globalOption.cgroupStat {
+		pg.outputCgroupStat(globalOption.cgroup, stats)
+	}
 
 	// invalid function, just make a reference relationship with pcstat
 	invalidCall()
 }
 
+// modeFlag returns the flag of the mode running instead of printing a
+// report of the page cache stats, or "".
+func modeFlag() string {
+	switch {
+	case globalOption.diff:
+		return "diff"
+	case globalOption.restore != "":
+		return "restore"
+	case globalOption.mount != "":
+		return "mount"
+	case globalOption.fstype != "":
+		return "fstype"
+	case globalOption.listen != "":
+		return "listen"
+	case globalOption.live:
+		return "live"
+	case globalOption.evict:
+		return "evict"
+	case globalOption.warm:
+		return "warm"
+	case globalOption.snapshot != "":
+		return "snapshot"
+	case globalOption.lock:
+		return "lock"
+	case globalOption.interval > 0:
+		return "interval"
+	}
+	return ""
+}
+
 func invalidCall() {
 	pcstat.SwitchMountNs(os.Getegid())
 	pcstat.GetPcStatus(os.Args[0])
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+	"github.com/rfyiamcool/pgcacher/pkg/psutils"
+)
+
+// mntnsFiles is the files of the processes in a mount namespace other than
+// ours. their paths are only meaningful inside of the namespace, so they are
+// measured from threads that entered it.
+type mntnsFiles struct {
+	ns    int    // inode of the namespace
+	label string // such as container:3f2a1b4c5d6e or mnt:[4026532448]
+	pids  []int  // processes in the namespace, the first one alive is entered
+	files []string
+}
+
+// mountNsLabel names the mount namespace of the pid after its container when
+// there is one.
+func mountNsLabel(pid, ns int) string {
+	if id := psutils.ContainerID(pid); id != "" {
+		return "container:" + id[:12]
+	}
+	return fmt.Sprintf("mnt:[%d]", ns)
+}
+
+// addProcessFiles records the files of a process, either in our own mount
+// namespace or in the group of its namespace. ns is 0 when it's unknown.
+// the deleted files are recorded apart, they are opened through /proc.
+func (pg *pgcacher) addProcessFiles(proc processFiles, ns int) {
+	var group *mntnsFiles
+	if ns != 0 && ns != pg.selfNs {
+		for i := range pg.nsFiles {
+			if pg.nsFiles[i].ns == ns {
+				group = &pg.nsFiles[i]
+				break
+			}
+		}
+		if group == nil {
+			pg.nsFiles = append(pg.nsFiles, mntnsFiles{ns: ns, label: mountNsLabel(proc.pid, ns)})
+			group = &pg.nsFiles[len(pg.nsFiles)-1]
+		}
+		group.pids = append(group.pids, proc.pid)
+		proc.mntns = group.label
+	}
+
+	for _, fname := range proc.files {
+		if link, ok := proc.links[fname]; ok {
+			pg.addHeldFile(proc.mntns, fname, link)
+		} else if group != nil {
+			group.files = append(group.files, fname)
+		} else {
+			pg.files = append(pg.files, fname)
+		}
+	}
+	pg.procs = append(pg.procs, proc)
+}
+
+// enterMountNs makes the calling thread enter the mount namespace of the
+// first pid still alive.
+func enterMountNs(pids []int) error {
+	err := errors.New("no process left in it")
+	for _, pid := range pids {
+		if err = pcstats.EnterMountNs(pid); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// fileKey identifies a file by its name and the mount namespace the name is
+// resolved in, the same path may be different files in two containers. it's
+// also how the name is shown, such as 'container:3f2a1b4c5d6e /etc/hosts'.
+func fileKey(mntns, name string) string {
+	if mntns == "" {
+		return name
+	}
+	return mntns + " " + name
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+const (
+	groupByMount  = "mount"
+	groupByFSType = "fstype"
+
+	unknownMount = "unknown"
+)
+
+// MountStat is the sum of the page cache stats of the files on a mount, or
+// on all mounts of a filesystem type.
+type MountStat struct {
+	MountPoint string  `json:"mount_point,omitempty"`
+	FSType     string  `json:"fstype"`
+	Source     string  `json:"source,omitempty"`
+	Mounts     int     `json:"mounts"`
+	Files      int     `json:"files"`
+	Size       int64   `json:"size"`
+	Pages      int64   `json:"pages"`
+	Cached     int64   `json:"cached"`      // cached pages
+	Bytes      int64   `json:"cached_size"` // cached bytes
+	Percent    float64 `json:"percent"`
+
+	stats PcStatusList // files on the mount, sorted by cached pages
+}
+
+type MountStatList struct {
+	byFSType bool
+	stats    []MountStat
+}
+
+// groupByMounts rolls the stats up into the mounts holding the files, found
+// by the device of the files, or into filesystem types. files of unknown
+// devices, e.g. memfd, are put into the unknown mount.
+func groupByMounts(stats PcStatusList, mounts []pcstats.Mount, byFSType bool) MountStatList {
+	index := make(map[string]int)
+	list := MountStatList{byFSType: byFSType}
+	seenMounts := make(map[string]map[int]emptyNull)
+	for _, pcs := range stats {
+		m, ok := pcstats.MountOf(mounts, pcs.Dev, pcs.Name)
+		if !ok {
+			m = pcstats.Mount{MountPoint: unknownMount, FSType: unknownMount}
+		}
+
+		key := m.MountPoint
+		if byFSType {
+			key = m.FSType
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(list.stats)
+			index[key] = i
+			ms := MountStat{FSType: m.FSType}
+			if !byFSType {
+				ms.MountPoint = m.MountPoint
+				ms.Source = m.Source
+			}
+			list.stats = append(list.stats, ms)
+			seenMounts[key] = make(map[int]emptyNull)
+		}
+		seenMounts[key][m.ID] = emptyNull{}
+
+		ms := &list.stats[i]
+		ms.Files++
+		ms.Size += pcs.Size
+		ms.Pages += int64(pcs.Pages)
+		ms.Cached += int64(pcs.Cached)
+		ms.Bytes += cachedSize(pcs)
+		ms.stats = append(ms.stats, pcs)
+	}
+
+	for i := range list.stats {
+		ms := &list.stats[i]
+		ms.Percent = percentOf(ms.Cached, ms.Pages)
+		ms.Mounts = len(seenMounts[ms.key(byFSType)])
+	}
+
+	sort.SliceStable(list.stats, func(i, j int) bool {
+		return list.stats[i].Cached > list.stats[j].Cached
+	})
+	return list
+}
+
+func (ms MountStat) key(byFSType bool) string {
+	if byFSType {
+		return ms.FSType
+	}
+	return ms.MountPoint
+}
+
+func (list MountStatList) FormatText() {
+	list.formatTable(textTable)
+}
+
+func (list MountStatList) FormatUnicode() {
+	list.formatTable(unicodeTable)
+}
+
+func (list MountStatList) FormatPlain() {
+	list.formatTable(plainTable)
+}
+
+func (list MountStatList) formatTable(style tableStyle) {
+	header := []string{"Mount Point", "FS Type", "Source"}
+	if list.byFSType {
+		header = []string{"FS Type", "Mounts"}
+	}
+	header = append(header, "Files", "Size", "Cached Size", "Percent")
+
+	row := func(ms MountStat) []string {
+		cells := []string{ms.MountPoint, ms.FSType, ms.Source}
+		if list.byFSType {
+			cells = []string{ms.FSType, strconv.Itoa(ms.Mounts)}
+		}
+		return append(cells,
+			strconv.Itoa(ms.Files),
+			ConvertUnit(ms.Size),
+			ConvertUnit(ms.Bytes),
+			fmt.Sprintf("%.3f", percentOf(ms.Cached, ms.Pages)),
+		)
+	}
+
+	sum := MountStat{MountPoint: "Sum", FSType: "Sum"}
+	rows := make([][]string, 0, len(list.stats))
+	for _, ms := range list.stats {
+		rows = append(rows, row(ms))
+
+		sum.Mounts += ms.Mounts
+		sum.Files += ms.Files
+		sum.Size += ms.Size
+		sum.Pages += ms.Pages
+		sum.Bytes += ms.Bytes
+		sum.Cached += ms.Cached
+	}
+	if !list.byFSType {
+		sum.FSType = ""
+	}
+
+	printTable(style, header, rows, row(sum))
+}
+
+func (list MountStatList) FormatTerse() {
+	fmt.Println("mount_point,fstype,source,mounts,files,size,pages,cached,percent")
+	for _, ms := range list.stats {
+		fmt.Printf("%s,%s,%s,%d,%d,%d,%d,%d,%g\n", ms.MountPoint, ms.FSType, ms.Source, ms.Mounts, ms.Files, ms.Size, ms.Pages, ms.Cached, ms.Percent)
+	}
+}
+
+func (list MountStatList) FormatJson() {
+	writeJSON(list.stats, false)
+}
+
+func (list MountStatList) FormatNDJson() {
+	writeJSON(list.stats, true)
+}
+
+// outputMounts prints the mounts or filesystem types, then the files of the
+// top drill ones, and how much of the page cache of the system they explain.
+func (pg *pgcacher) outputMounts(stats PcStatusList, groupBy string, limit, drill int) {
+	mounts, err := pcstats.ReadMounts()
+	if err != nil {
+		log.Fatalf("failed to read mounts, err: %v", err)
+	}
+
+	list := groupByMounts(stats, mounts, groupBy == groupByFSType)
+	list.stats = list.stats[:min(len(list.stats), limit)]
+
+	pg.format(list)
+
+	for _, ms := range list.stats[:min(len(list.stats), drill)] {
+		pg.outputTitle(ms.key(list.byFSType))
+		pg.output(ms.stats, limit)
+	}
+
+	pg.outputMeminfo(stats, mounts)
+}
+
+// outputMeminfo compares the cached bytes of the files with the page cache
+// of the system. the rest of Cached belongs to files no scanned process
+// holds, or that were not given.
+func (pg *pgcacher) outputMeminfo(stats PcStatusList, mounts []pcstats.Mount) {
+	info, err := pcstats.ReadMeminfo()
+	if err != nil {
+		log.Printf("failed to read /proc/meminfo, err: %v", err)
+		return
+	}
+
+	var cached, shmem int64
+	var otherNs int
+	for _, pcs := range stats {
+		cached += cachedSize(pcs)
+		if m, ok := pcstats.MountOf(mounts, pcs.Dev, pcs.Name); !ok || isShmemMount(m) {
+			shmem += cachedSize(pcs)
+		}
+		if pcs.MountNs != "" {
+			otherNs++
+		}
+	}
+
+	out := pg.reportWriter()
+
+	fmt.Fprintf(out, "meminfo: cached %s (shmem %s), buffers %s\n",
+		humanize.IBytes(uint64(info.Cached)), humanize.IBytes(uint64(info.Shmem)), humanize.IBytes(uint64(info.Buffers)))
+	fmt.Fprintf(out, "pgcacher found %s cached in %d files (shmem %s), %.3f%% of cached, %s unexplained\n",
+		humanize.IBytes(uint64(cached)), len(stats), humanize.IBytes(uint64(shmem)),
+		percentOf(cached, info.Cached), humanize.IBytes(uint64(max64(info.Cached-cached, 0))))
+	if otherNs > 0 {
+		fmt.Fprintf(out, "%d files are in other mount namespaces, their mounts are looked up in the mounts of pgcacher's own namespace\n", otherNs)
+	}
+}
+
+// isShmemMount tells whether the files of the mount are counted as Shmem in
+// /proc/meminfo, such as tmpfs, devtmpfs and the /dev/shm of containers.
+func isShmemMount(m pcstats.Mount) bool {
+	switch m.FSType {
+	case "tmpfs", "devtmpfs", "shm":
+		return true
+	}
+	return m.Source == "shm" || m.MountPoint == "/dev/shm"
+}
+
+func max64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -5,10 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -20,11 +20,27 @@ import (
 type emptyNull struct{}
 
 type pgcacher struct {
-	files     []string
+	args      []string             // files given on the command line
+	dirs      []string             // dirs given on the command line, walked on every scan
+	files     []string             // files in our own mount namespace
+	nsFiles   []mntnsFiles         // files in the mount namespaces of other processes
+	held      map[string]heldFiles // deleted files held by processes, by mount namespace
+	selfNs    int
+	procs     []processFiles
 	leastSize int64
 	option    *option
 }
 
+// processFiles is the files opened or mapped by a process.
+type processFiles struct {
+	pid   int
+	exe   string
+	rss   int64  // resident pages
+	mntns string // label of the mount namespace, empty for our own
+	files []string
+	links map[string]string // deleted files, to the /proc link they are opened through
+}
+
 func (pg *pgcacher) ignoreFile(file string) bool {
 	if pg.option.excludeFiles != "" && wildcardMatch(file, pg.option.excludeFiles) {
 		return true
@@ -38,8 +54,17 @@ func (pg *pgcacher) ignoreFile(file string) bool {
 }
 
 func (pg *pgcacher) filterFiles() {
-	sset := make(map[string]emptyNull, len(pg.files))
-	for _, file := range pg.files {
+	pg.files = pg.uniqueFiles(pg.files)
+	for i := range pg.nsFiles {
+		pg.nsFiles[i].files = pg.uniqueFiles(pg.nsFiles[i].files)
+	}
+	pg.filterHeldFiles()
+}
+
+// uniqueFiles removes the ignored and duplicated files.
+func (pg *pgcacher) uniqueFiles(files []string) []string {
+	sset := make(map[string]emptyNull, len(files))
+	for _, file := range files {
 		file = strings.Trim(file, " ")
 		if pg.ignoreFile(file) {
 			continue
@@ -52,30 +77,102 @@ func (pg *pgcacher) filterFiles() {
 	for fname := range sset {
 		dups = append(dups, fname)
 	}
-	pg.files = dups
+	return dups
+}
+
+// hasFiles returns true when any file is selected, in any mount namespace.
+func (pg *pgcacher) hasFiles() bool {
+	if len(pg.files) > 0 || len(pg.dirs) > 0 {
+		return true
+	}
+	for _, group := range pg.nsFiles {
+		if len(group.files) > 0 {
+			return true
+		}
+	}
+	for _, files := range pg.held {
+		if len(files) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// selectFiles resets the files to the command line files, appends the files
+// of the selected processes and applies the filters. it fails when the
+// selected processes are gone, the files found are selected anyway.
+func (pg *pgcacher) selectFiles() error {
+	pg.files = append([]string(nil), pg.args...)
+	pg.nsFiles = nil
+	pg.held = nil
+	pg.procs = nil
+	pg.selfNs = pcstats.MountNs(os.Getpid())
+
+	var err error
+	if pg.option.top {
+		pg.appendTopFiles()
+	} else if pg.option.container != "" {
+		err = pg.appendContainerFiles(pg.option.container)
+	} else {
+		if pg.option.cgroup != "" {
+			pg.appendCgroupFiles(pg.option.cgroup)
+		}
+		if pg.hasProcessSelector() {
+			err = pg.appendSelectedFiles()
+		}
+		if pg.option.pid != 0 && pg.option.children {
+			if treeErr := pg.appendProcessTreeFiles(pg.option.pid); err == nil {
+				err = treeErr
+			}
+		} else if pg.option.pid != 0 {
+			pg.appendProcessFiles(pg.option.pid)
+		}
+	}
+
+	pg.filterFiles()
+	return err
+}
+
+// scansProcesses returns true when files are selected from processes,
+// which open and close files over time.
+func (pg *pgcacher) scansProcesses() bool {
+	return pg.option.top || pg.option.pid != 0 || pg.option.cgroup != "" || pg.option.container != "" ||
+		pg.hasProcessSelector()
 }
 
 func (pg *pgcacher) appendProcessFiles(pid int) {
-	pg.files = append(pg.files, pg.getProcessFiles(pid)...)
+	var (
+		exe string
+		rss int64
+	)
+	if proc, err := psutils.FindProcess(pid); err == nil && proc != nil {
+		exe = proc.Executable()
+		rss = int64(proc.RSS())
+	}
+
+	files, links := pg.getProcessFiles(pid)
+	pg.addProcessFiles(processFiles{pid: pid, exe: exe, rss: rss, files: files, links: links}, pcstats.MountNs(pid))
 }
 
-func (pg *pgcacher) getProcessFiles(pid int) []string {
-	// switch mount namespace for container.
-	pcstats.SwitchMountNs(pg.option.pid)
+// getProcessFiles returns the paths of the files of the process, they are
+// resolved in the mount namespace of the process. the deleted files are
+// also returned with the /proc links to open them.
+func (pg *pgcacher) getProcessFiles(pid int) ([]string, map[string]string) {
+	links := make(map[string]string)
 
 	// get files of `/proc/{pid}/fd` and `/proc/{pid}/maps`
-	processFiles := pg.getProcessFdFiles(pid)
-	processMapFiles := pg.getProcessMaps(pid)
+	processFiles := pg.getProcessFdFiles(pid, links)
+	processMapFiles := pg.getProcessMaps(pid, links)
 
 	// append
 	var files []string
 	files = append(files, processFiles...)
 	files = append(files, processMapFiles...)
 
-	return files
+	return files, links
 }
 
-func (pg *pgcacher) getProcessMaps(pid int) []string {
+func (pg *pgcacher) getProcessMaps(pid int, links map[string]string) []string {
 	fname := fmt.Sprintf("/proc/%d/maps", pid)
 
 	f, err := os.Open(fname)
@@ -89,12 +186,15 @@ func (pg *pgcacher) getProcessMaps(pid int) []string {
 
 	out := make([]string, 0, 20)
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) == 6 && strings.HasPrefix(parts[5], "/") {
-			// found something that looks like a file
-			out = append(out, parts[5])
+		addr, fname, ok := parseMapsLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		if isDeleted(fname) {
+			links[fname] = fmt.Sprintf("/proc/%d/map_files/%s", pid, addr)
 		}
+		out = append(out, fname)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -104,7 +204,35 @@ func (pg *pgcacher) getProcessMaps(pid int) []string {
 	return out
 }
 
-func (pg *pgcacher) getProcessFdFiles(pid int) []string {
+// pseudoMapPrefixes is the paths shown in /proc/<pid>/maps for shared
+// anonymous memory, which are not files even though they have an inode.
+// memfd is a file, it's measured through map_files like a deleted file.
+var pseudoMapPrefixes = []string{"/dev/zero", "/SYSV"}
+
+// parseMapsLine returns the address range and the pathname of a line of
+// /proc/<pid>/maps, ok is false when the mapping is not backed by a file.
+func parseMapsLine(line string) (addr, fname string, ok bool) {
+	// address perms offset dev inode pathname, the pathname may have
+	// spaces and the " (deleted)" suffix.
+	parts := strings.Fields(line)
+	start := strings.IndexByte(line, '/')
+	if len(parts) < 6 || start < 0 {
+		return "", "", false
+	}
+	if parts[3] == "00:00" || parts[4] == "0" {
+		return "", "", false
+	}
+
+	fname = line[start:]
+	for _, prefix := range pseudoMapPrefixes {
+		if strings.HasPrefix(fname, prefix) {
+			return "", "", false
+		}
+	}
+	return parts[0], fname, true
+}
+
+func (pg *pgcacher) getProcessFdFiles(pid int, links map[string]string) []string {
 	dpath := fmt.Sprintf("/proc/%d/fd", pid)
 
 	files, err := os.ReadDir(dpath)
@@ -137,6 +265,9 @@ func (pg *pgcacher) getProcessFdFiles(pid int) []string {
 		}
 
 		mu.Lock()
+		if isDeleted(target) {
+			links[target] = fpath
+		}
 		out = append(out, target)
 		mu.Unlock()
 	}
@@ -168,20 +299,203 @@ func (pg *pgcacher) getProcessFdFiles(pid int) []string {
 var errLessThanSize = errors.New("the file size is less than the leastSize")
 
 func (pg *pgcacher) getPageCacheStats() PcStatusList {
-	var (
-		mu = sync.Mutex{}
-		wg = sync.WaitGroup{}
+	stats := pg.measureDirs()
+	stats = append(stats, pg.measureFiles(pg.files, "", nil, nil)...)
+	for _, group := range pg.nsFiles {
+		stats = append(stats, pg.measureFiles(group.files, group.label, group.pids, nil)...)
+	}
+	stats = append(stats, pg.measureHeldFiles()...)
 
-		stats = make(PcStatusList, 0, len(pg.files))
+	// show which processes hold each file when scanning several of them.
+	if pg.option.top || len(pg.procs) > 1 {
+		pg.attachProcesses(stats)
+	}
+	stats = dedupFiles(stats)
+
+	sort.Sort(PcStatusList(stats))
+	return stats
+}
+
+// measureFiles gets the page cache stats of the files concurrently. when
+// pids are given, the files are in their mount namespace labelled mntns.
+// when links are given, the files are deleted and opened through them.
+func (pg *pgcacher) measureFiles(files []string, mntns string, pids []int, links heldFiles) PcStatusList {
+	var (
+		mu    = sync.Mutex{}
+		stats = make(PcStatusList, 0, len(files))
 	)
 
 	// fill files to queue.
-	queue := make(chan string, len(pg.files))
-	for _, fname := range pg.files {
+	queue := make(chan string, len(files))
+	for _, fname := range files {
 		queue <- fname
 	}
 	close(queue)
 
+	pg.measureQueue(queue, min(pg.option.worker, len(files)), mntns, pids, links, func(status pcstats.PcStatus) {
+		mu.Lock()
+		stats = append(stats, status)
+		mu.Unlock()
+	})
+	return stats
+}
+
+// measureDirs gets the page cache stats of the files under the dirs, they
+// are measured while the dirs are walked.
+func (pg *pgcacher) measureDirs() PcStatusList {
+	if len(pg.dirs) == 0 {
+		return nil
+	}
+
+	var (
+		mu    = sync.Mutex{}
+		stats PcStatusList
+		queue = make(chan string, 1024)
+	)
+
+	go func() {
+		walker := newDirWalker(pg.option.worker, pg.option.depth, pg.option.followSymlinks, false, pg.ignoreFile)
+		walker.walk(pg.dirs, queue)
+		close(queue)
+	}()
+
+	pg.measureQueue(queue, pg.option.worker, "", nil, nil, func(status pcstats.PcStatus) {
+		mu.Lock()
+		stats = append(stats, status)
+		mu.Unlock()
+	})
+	return stats
+}
+
+// setFiles replaces the selected files with the measured ones, e.g. the
+// files that have been warmed, so only they are measured again. the files
+// stay in their mount namespaces and the deleted files keep their links.
+func (pg *pgcacher) setFiles(stats PcStatusList) {
+	keep := make(map[string]emptyNull, len(stats))
+	files := make([]string, 0, len(stats))
+	for _, status := range stats {
+		keep[fileKey(status.MountNs, status.Name)] = emptyNull{}
+		if status.MountNs == "" && !status.Deleted {
+			files = append(files, status.Name)
+		}
+	}
+
+	pg.files = files
+	pg.dirs = nil
+	for i := range pg.nsFiles {
+		group := &pg.nsFiles[i]
+		gfiles := make([]string, 0, len(group.files))
+		for _, fname := range group.files {
+			if _, ok := keep[fileKey(group.label, fname)]; ok {
+				gfiles = append(gfiles, fname)
+			}
+		}
+		group.files = gfiles
+	}
+	for mntns, files := range pg.held {
+		for fname := range files {
+			if _, ok := keep[fileKey(mntns, fname)]; !ok {
+				delete(files, fname)
+			}
+		}
+	}
+}
+
+// forEachFile calls fn with the measured files and the path to open each of
+// them with, by workers per mount namespace. the files of other mount
+// namespaces are opened by name from threads that entered the namespace,
+// the deleted files through their /proc links, until one works. the failures
+// are logged with the verb, only the files fn succeeded with are returned.
+func (pg *pgcacher) forEachFile(stats PcStatusList, workers int, verb string, fn func(status pcstats.PcStatus, fname string) error) PcStatusList {
+	var (
+		wg     = sync.WaitGroup{}
+		mu     = sync.Mutex{}
+		done   = make(PcStatusList, 0, len(stats))
+		queues = make(map[string]chan pcstats.PcStatus)
+	)
+	if workers <= 0 {
+		workers = 1
+	}
+
+	handle := func(status pcstats.PcStatus) {
+		fnames := []string{status.Name}
+		if status.Deleted {
+			fnames = pg.held[status.MountNs][status.Name]
+		}
+
+		err := errors.New("no link to open it")
+		for _, fname := range fnames {
+			if err = fn(status, fname); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Printf("failed to %s %q: %v", verb, fileKey(status.MountNs, status.Name), err)
+			return
+		}
+
+		mu.Lock()
+		done = append(done, status)
+		mu.Unlock()
+	}
+
+	// the deleted files are opened through our own /proc.
+	queueOf := func(status pcstats.PcStatus) chan pcstats.PcStatus {
+		var mntns string
+		if !status.Deleted {
+			mntns = status.MountNs
+		}
+		if queue, ok := queues[mntns]; ok {
+			return queue
+		}
+
+		var pids []int
+		for _, group := range pg.nsFiles {
+			if group.label == mntns {
+				pids = group.pids
+			}
+		}
+
+		queue := make(chan pcstats.PcStatus, len(stats))
+		queues[mntns] = queue
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				// the thread is never unlocked, same as measureQueue.
+				if mntns != "" {
+					runtime.LockOSThread()
+					if err := enterMountNs(pids); err != nil {
+						log.Printf("skipping the files of %s, failed to enter its mount namespace, err: %v", mntns, err)
+						return
+					}
+				}
+
+				for status := range queue {
+					handle(status)
+				}
+			}()
+		}
+		return queue
+	}
+
+	for _, status := range stats {
+		queueOf(status) <- status
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	return done
+}
+
+// measureQueue gets the page cache stats of the files from the queue with
+// the workers until it's closed, emit is called concurrently with the stats.
+func (pg *pgcacher) measureQueue(queue <-chan string, workers int, mntns string, pids []int, links heldFiles, emit func(pcstats.PcStatus)) {
+	wg := sync.WaitGroup{}
+
 	ignoreFunc := func(file *os.File) error {
 		fs, err := file.Stat()
 		if err != nil {
@@ -194,7 +508,22 @@ func (pg *pgcacher) getPageCacheStats() PcStatusList {
 	}
 
 	analyse := func(fname string) {
-		status, err := pcstats.GetPcStatus(fname, ignoreFunc)
+		var (
+			status pcstats.PcStatus
+			err    error
+		)
+		if links == nil {
+			status, err = pcstats.GetPcStatus(fname, ignoreFunc)
+		} else {
+			// the processes may have closed the file or exited.
+			for _, link := range links[fname] {
+				status, err = pcstats.GetPcStatusByLink(fname, link, ignoreFunc)
+				if err == nil || err == errLessThanSize {
+					break
+				}
+			}
+			status.Deleted = true
+		}
 		if err == errLessThanSize {
 			return
 		}
@@ -202,53 +531,118 @@ func (pg *pgcacher) getPageCacheStats() PcStatusList {
 			log.Printf("skipping %q: %v", fname, err)
 			return
 		}
+		status.MountNs = mntns
 
-		// only get filename, trim full dir path of the file.
-		if pg.option.bname {
-			status.Name = path.Base(fname)
-		}
-
-		// append
-		mu.Lock()
-		stats = append(stats, status)
-		mu.Unlock()
+		emit(status)
 	}
 
 	// analyse page cache stats of files concurrently.
-	for i := 0; i < pg.option.worker; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
+			// the thread is never unlocked, so it exits with the goroutine
+			// rather than going back to the pool in the wrong namespace.
+			if len(pids) > 0 {
+				runtime.LockOSThread()
+				if err := enterMountNs(pids); err != nil {
+					log.Printf("skipping the files of %s, failed to enter its mount namespace, err: %v", mntns, err)
+					return
+				}
+			}
+
 			for fname := range queue {
 				analyse(fname)
 			}
 		}()
 	}
 	wg.Wait()
+}
 
-	sort.Sort(PcStatusList(stats))
-	return stats
+// attachProcesses fills the processes holding each file, by pid.
+func (pg *pgcacher) attachProcesses(stats PcStatusList) {
+	holders := make(map[string][]pcstats.Holder)
+	for _, proc := range pg.procs {
+		seen := make(map[string]emptyNull, len(proc.files))
+		for _, fname := range proc.files {
+			if _, ok := seen[fname]; ok {
+				continue
+			}
+			seen[fname] = emptyNull{}
+			key := fileKey(proc.mntns, fname)
+			holders[key] = append(holders[key], pcstats.Holder{Pid: proc.pid, Executable: proc.exe})
+		}
+	}
+
+	for i := range stats {
+		hs := holders[fileKey(stats[i].MountNs, stats[i].Name)]
+		sort.Slice(hs, func(a, b int) bool { return hs[a].Pid < hs[b].Pid })
+		stats[i].Processes = hs
+	}
 }
 
 func (pg *pgcacher) output(stats PcStatusList, limit int) {
 	limit = min(len(stats), limit)
 	stats = stats[:limit]
 
-	if pg.option.json {
-		stats.FormatJson()
+	nstats := make(PcStatusList, len(stats))
+	for i, status := range stats {
+		nstats[i] = pg.displayStatus(status)
+	}
+	pg.format(nstats)
+}
+
+// displayStatus trims the full dir path of the file when only the filename
+// is wanted. files in other mount namespaces are prefixed with the namespace
+// and the number of aliases is appended, json has fields for them.
+func (pg *pgcacher) displayStatus(status pcstats.PcStatus) pcstats.PcStatus {
+	labelNs := !pg.option.json && !pg.option.ndjson
+	if pg.option.bname {
+		status.Name = path.Base(status.Name)
+	}
+	if labelNs && status.MountNs != "" {
+		status.Name = fileKey(status.MountNs, status.Name)
+	}
+	if labelNs && !pg.option.terse {
+		status.Name += aliasesNote(status.Aliases)
+	}
+	return status
+}
+
+// formatter is a list of stats which can be printed in every output format.
+type formatter interface {
+	FormatText()
+	FormatUnicode()
+	FormatPlain()
+	FormatTerse()
+	FormatJson()
+	FormatNDJson()
+}
+
+// format prints the list in the output format of the options, lists without
+// a histogram are printed as text with -histo.
+func (pg *pgcacher) format(list formatter) {
+	histo, hasHisto := list.(interface{ FormatHisto() })
+
+	if pg.option.ndjson {
+		list.FormatNDJson()
+	} else if pg.option.json {
+		list.FormatJson()
 	} else if pg.option.terse {
-		stats.FormatTerse()
+		list.FormatTerse()
 	} else if pg.option.unicode {
-		stats.FormatUnicode()
+		list.FormatUnicode()
 	} else if pg.option.plain {
-		stats.FormatPlain()
+		list.FormatPlain()
+	} else if pg.option.histo && hasHisto {
+		histo.FormatHisto()
 	} else {
-		stats.FormatText()
+		list.FormatText()
 	}
 }
 
-func (pg *pgcacher) handleTop() {
+func (pg *pgcacher) appendTopFiles() {
 	// get all active process.
 	procs, err := psutils.Processes()
 	if err != nil || len(procs) == 0 {
@@ -264,6 +658,85 @@ func (pg *pgcacher) handleTop() {
 		ps = append(ps, proc)
 	}
 
+	pg.appendProcessesFiles(ps)
+}
+
+// appendProcessTreeFiles appends the files of the process and all of its
+// descendants, pre-fork servers keep most files open in the children.
+func (pg *pgcacher) appendProcessTreeFiles(pid int) error {
+	procs, err := psutils.Processes()
+	if err != nil {
+		log.Fatalf("failed to get processes, err: %v", err)
+	}
+
+	var ps []psutils.Process
+	for _, proc := range procs {
+		if proc.Pid() == pid {
+			ps = append(ps, proc)
+			break
+		}
+	}
+	if len(ps) == 0 {
+		return fmt.Errorf("process %d not found", pid)
+	}
+
+	pg.appendProcessesFiles(append(ps, psutils.Descendants(pid, procs)...))
+	return nil
+}
+
+// appendCgroupFiles appends the files of all processes in the cgroup and
+// its children.
+func (pg *pgcacher) appendCgroupFiles(cgroup string) {
+	dir, err := psutils.CgroupDir(cgroup)
+	if err != nil {
+		log.Fatalf("failed to find cgroup, err: %v", err)
+	}
+
+	pids, err := psutils.CgroupPids(dir)
+	if err != nil {
+		log.Fatalf("failed to get processes of cgroup %s, err: %v", dir, err)
+	}
+
+	ps := make([]psutils.Process, 0, len(pids))
+	for _, pid := range pids {
+		proc, err := psutils.FindProcess(pid)
+		if err != nil || proc == nil {
+			continue // exited
+		}
+		ps = append(ps, proc)
+	}
+
+	pg.appendProcessesFiles(ps)
+}
+
+// appendContainerFiles appends the files of all processes of the container,
+// found by the container id in their cgroup.
+func (pg *pgcacher) appendContainerFiles(container string) error {
+	id := psutils.ResolveContainerID(container)
+	pids, err := psutils.ContainerPids(id)
+	if err != nil {
+		return fmt.Errorf("failed to get processes of container %s, err: %v", container, err)
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no process found in container %s", container)
+	}
+
+	ps := make([]psutils.Process, 0, len(pids))
+	for _, pid := range pids {
+		proc, err := psutils.FindProcess(pid)
+		if err != nil || proc == nil {
+			continue // exited
+		}
+		ps = append(ps, proc)
+	}
+
+	pg.appendProcessesFiles(ps)
+	return nil
+}
+
+// appendProcessesFiles appends the open fd and mapped files of each process
+// concurrently.
+func (pg *pgcacher) appendProcessesFiles(ps []psutils.Process) {
 	var (
 		wg    = sync.WaitGroup{}
 		mu    = sync.Mutex{}
@@ -282,25 +755,17 @@ func (pg *pgcacher) handleTop() {
 			defer wg.Done()
 
 			for process := range queue {
-				files := pg.getProcessFiles(process.Pid())
+				files, links := pg.getProcessFiles(process.Pid())
+				ns := pcstats.MountNs(process.Pid())
 
 				mu.Lock()
-				pg.files = append(pg.files, files...)
+				pg.addProcessFiles(processFiles{pid: process.Pid(), exe: process.Executable(), rss: int64(process.RSS()), files: files, links: links}, ns)
 				mu.Unlock()
 			}
 
 		}()
 	}
 	wg.Wait()
-
-	// filter files
-	pg.filterFiles()
-
-	// get page cache stats of files.
-	stats := pg.getPageCacheStats()
-
-	// print
-	pg.output(stats, pg.option.limit)
 }
 
 func min(x, y int) int {
@@ -359,59 +824,3 @@ func wildcardMatch(s string, p string) bool {
 
 	return isMatchingMatrix[lenInput][lenPattern]
 }
-
-func walkDirs(dirs []string, maxDepth int) []string {
-	if len(dirs) == 0 {
-		return dirs
-	}
-
-	var files []string
-	for _, dir := range dirs {
-		fi, err := os.Open(dir)
-		if err != nil {
-			files = append(files, dir)
-			continue
-		}
-
-		fs, err := fi.Stat()
-		if err != nil {
-			files = append(files, dir)
-			continue
-		}
-
-		// is dir
-		if fs.IsDir() {
-			files = append(files, walkDir(dir, 0, maxDepth)...)
-			continue
-		}
-
-		// is file
-		files = append(files, dir)
-	}
-
-	return files
-}
-
-func walkDir(dir string, depth int, maxDepth int) []string {
-	if depth >= maxDepth {
-		return nil
-	}
-
-	var files []string
-	ofiles, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil
-	}
-
-	for _, file := range ofiles {
-		curdir := path.Join(dir, file.Name())
-		if file.IsDir() {
-			files = append(files, walkDir(curdir, depth+1, maxDepth)...)
-			continue
-		}
-
-		files = append(files, curdir)
-	}
-
-	return files
-}
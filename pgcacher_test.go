@@ -1,11 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+	"github.com/rfyiamcool/pgcacher/pkg/psutils"
 	"github.com/stretchr/testify/assert"
 	ppc "github.com/tobert/pcstat/pkg"
+	"golang.org/x/sys/unix"
 )
 
 func TestMatch(t *testing.T) {
@@ -23,3 +35,631 @@ func TestNull(t *testing.T) {
 
 	t.Logf("%s %v", stat.Name, stat.Cached)
 }
+
+func TestWindowedMincore(t *testing.T) {
+	f, err := os.Open(os.Args[0])
+	assert.Nil(t, err)
+	defer f.Close()
+
+	fs, err := f.Stat()
+	assert.Nil(t, err)
+
+	whole, err := pcstats.GetFileMincore(f, fs.Size())
+	assert.Nil(t, err)
+
+	// odd window size, not a multiple of the page size.
+	defer func(size int64) { pcstats.WindowSize = size }(pcstats.WindowSize)
+	pcstats.WindowSize = int64(os.Getpagesize())*3 + 100
+
+	windowed, err := pcstats.GetFileMincore(f, fs.Size())
+	assert.Nil(t, err)
+	assert.Equal(t, whole.Cached+whole.Miss, windowed.Cached+windowed.Miss)
+	assert.Equal(t, whole.Cached, windowed.Cached)
+}
+
+// writeTempFile writes a file of the given pages, synced to disk or left
+// dirty in the page cache.
+func writeTempFile(t *testing.T, pages int, sync bool) string {
+	f, err := os.CreateTemp(t.TempDir(), "pgcacher")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	_, err = f.Write(make([]byte, pages*os.Getpagesize()))
+	assert.Nil(t, err)
+	if sync {
+		assert.Nil(t, f.Sync())
+	}
+	return f.Name()
+}
+
+func TestCachestatDirty(t *testing.T) {
+	fname := writeTempFile(t, 4, false)
+	f, err := os.Open(fname)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	cstat, ok, err := pcstats.GetFileCachestat(f)
+	assert.Nil(t, err)
+	if !ok {
+		t.Skip("cachestat(2) is not supported")
+	}
+	assert.True(t, cstat.Dirty > 0)
+	assert.True(t, cstat.Cached > 0)
+}
+
+func TestEvict(t *testing.T) {
+	fname := writeTempFile(t, 16, true)
+	before, err := pcstats.GetPcStatus(fname, func(f *os.File) error { return nil })
+	assert.Nil(t, err)
+	assert.True(t, before.Cached > 0)
+
+	pg := pgcacher{files: []string{fname}, option: &option{worker: 2, limit: 10, terse: true}}
+	pg.handleEvict()
+
+	after, err := pcstats.GetPcStatus(fname, func(f *os.File) error { return nil })
+	assert.Nil(t, err)
+	assert.True(t, after.Cached < before.Cached)
+}
+
+func TestWarmFile(t *testing.T) {
+	fname := writeTempFile(t, 16, true)
+	cached := func() int {
+		pcs, err := pcstats.GetPcStatus(fname, func(f *os.File) error { return nil })
+		assert.Nil(t, err)
+		return pcs.Cached
+	}
+
+	for _, strategy := range []pcstats.WarmStrategy{pcstats.WarmWillNeed, pcstats.WarmReadahead, pcstats.WarmRead} {
+		assert.Nil(t, pcstats.EvictFile(fname))
+		assert.Equal(t, 0, cached(), strategy)
+
+		var done int64
+		assert.Nil(t, pcstats.WarmFile(fname, strategy, func(n int64) { done += n }), strategy)
+		assert.Equal(t, int64(16*os.Getpagesize()), done, strategy)
+
+		// willneed only starts the reads.
+		assert.Eventually(t, func() bool { return cached() == 16 }, time.Second, 10*time.Millisecond, strategy)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	pcstats.KeepPageRanges = true
+	defer func() { pcstats.KeepPageRanges = false }()
+
+	fname := writeTempFile(t, 16, true)
+	snapName := filepath.Join(t.TempDir(), "snapshot.json")
+	cached := func() int {
+		pcs, err := pcstats.GetPcStatus(fname, func(f *os.File) error { return nil })
+		assert.Nil(t, err)
+		return pcs.Cached
+	}
+
+	pg := pgcacher{files: []string{fname}, option: &option{worker: 2, limit: 10, terse: true}}
+	pg.handleSnapshot(snapName)
+
+	b, err := os.ReadFile(snapName)
+	assert.Nil(t, err)
+	var snap snapshot
+	assert.Nil(t, json.Unmarshal(b, &snap))
+	assert.Equal(t, 1, len(snap.Files))
+	assert.Equal(t, fname, snap.Files[0].Path)
+	assert.Equal(t, []pcstats.PageRange{{Start: 0, End: 16}}, snap.Files[0].Ranges)
+
+	assert.Nil(t, pcstats.EvictFile(fname))
+	assert.Equal(t, 0, cached())
+	pg.handleRestore(snapName, pcstats.WarmRead)
+	assert.Equal(t, 16, cached())
+
+	// a file replaced by another one of the same size isn't restored.
+	other := writeTempFile(t, 16, true)
+	assert.Nil(t, os.Chtimes(other, snap.Files[0].Mtime, snap.Files[0].Mtime))
+	assert.Nil(t, os.Rename(other, fname))
+	assert.Nil(t, pcstats.EvictFile(fname))
+	pg.handleRestore(snapName, pcstats.WarmRead)
+	assert.Equal(t, 0, cached())
+
+	finfo, err := os.Stat(fname)
+	assert.Nil(t, err)
+	err = snap.Files[0].verify(finfo)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "device or inode changed")
+}
+
+func TestCachedRanges(t *testing.T) {
+	defer func(keep bool) { pcstats.KeepPageRanges = keep }(pcstats.KeepPageRanges)
+	pcstats.KeepPageRanges = true
+
+	stat, err := pcstats.GetPcStatus(os.Args[0], func(f *os.File) error { return nil })
+	assert.Nil(t, err)
+
+	var cached int64
+	for _, r := range stat.CachedRanges {
+		assert.True(t, r.Start < r.End)
+		cached += r.End - r.Start
+	}
+	assert.Equal(t, int64(stat.Cached), cached)
+}
+
+func TestHistoCells(t *testing.T) {
+	ranges := []pcstats.PageRange{{Start: 0, End: 10}, {Start: 15, End: 20}}
+
+	cells := histoCells(ranges, 40, 4)
+	assert.Equal(t, []float64{1, 0.5, 0, 0}, cells)
+
+	// fewer pages than cells.
+	cells = histoCells([]pcstats.PageRange{{Start: 1, End: 2}}, 2, 4)
+	assert.Equal(t, []float64{0, 0, 1, 1}, cells)
+
+	assert.Equal(t, "█▄  ", histoStrip(pcstats.PcStatus{Pages: 40, CachedRanges: ranges}, 4))
+}
+
+func TestLiveView(t *testing.T) {
+	pg := pgcacher{option: &option{}}
+	view := newLiveView(pg.displayStatus)
+	view.update(PcStatusList{
+		{Name: "/data/a", Size: 100, Percent: 50},
+		{Name: "/data/b", Size: 400, Percent: 10},
+		{Name: "/data/b", MountNs: "mnt:[2]", Size: 400, Percent: 100},
+	})
+	view.update(PcStatusList{
+		{Name: "/data/a", Size: 100, Percent: 100},
+		{Name: "/data/b", Size: 400, Percent: 10},
+		{Name: "/data/b", MountNs: "mnt:[2]", Size: 400, Percent: 50},
+		{Name: "/logs/c", Size: 10, Percent: 100},
+	})
+
+	rows := view.visibleRows()
+	assert.Equal(t, "mnt:[2]", rows[0].MountNs)
+	assert.Equal(t, int64(-200), rows[0].delta)
+	assert.Equal(t, "/data/a", rows[1].Name)
+	assert.Equal(t, int64(50), rows[1].delta)
+	assert.Equal(t, "", rows[2].MountNs)
+	assert.Equal(t, int64(0), rows[2].delta)
+
+	var buf bytes.Buffer
+	view.render(&buf, 120, 40, time.Second)
+	assert.Contains(t, buf.String(), "mnt:[2] /data/b")
+
+	view.handleKey('s')
+	assert.Equal(t, "/data/b", view.visibleRows()[0].Name)
+	assert.Equal(t, "/data/b", view.visibleRows()[1].Name)
+
+	for _, b := range []byte("/logs\r") {
+		view.handleKey(b)
+	}
+	rows = view.visibleRows()
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "/logs/c", rows[0].Name)
+
+	assert.True(t, view.handleKey('q'))
+}
+
+func TestDeltaList(t *testing.T) {
+	now := time.Now()
+	prev := PcStatusList{
+		{Name: "a", Cached: 10, Timestamp: now},
+		{Name: "b", Cached: 10, Timestamp: now},
+		{Name: "d", Pages: 4, Cached: 2, Percent: 50, Timestamp: now},
+	}
+	cur := PcStatusList{
+		{Name: "a", Cached: 30, Timestamp: now.Add(2 * time.Second)},
+		{Name: "b", Cached: 5, Timestamp: now.Add(time.Second)},
+		{Name: "c", Cached: 7, Timestamp: now.Add(time.Second)},
+	}
+
+	pageSize := float64(os.Getpagesize())
+	deltas := newPcStatusDeltaList(prev, cur)
+	assert.Equal(t, 20, deltas[0].Delta)
+	assert.Equal(t, 10*pageSize, deltas[0].Rate)
+	assert.Equal(t, -5, deltas[1].Delta)
+	assert.Equal(t, -5*pageSize, deltas[1].Rate)
+	assert.Equal(t, 7, deltas[2].Delta)
+	assert.Equal(t, changeAppeared, deltas[2].Change)
+	assert.Equal(t, "d", deltas[3].Name)
+	assert.Equal(t, changeDisappeared, deltas[3].Change)
+	assert.Equal(t, -2, deltas[3].Delta)
+	assert.Equal(t, -50.0, deltas[3].PercentDelta)
+	assert.Equal(t, 0, deltas[3].Cached)
+
+	// the first sample has nothing to compare with.
+	deltas = newPcStatusDeltaList(nil, cur)
+	assert.Equal(t, 0, deltas[2].Delta)
+	assert.Equal(t, "", deltas[2].Change)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	stats := PcStatusList{
+		{Name: "/data/a", Size: 200, Percent: 50},
+		{Name: "/data/b\"x", Size: 100, Percent: 100},
+		{Name: "/logs/c", Size: 100, Percent: 10},
+	}
+	procs := []processFiles{
+		{pid: 1, exe: "init", files: []string{"/data/a", "/data/a", "/logs/c"}},
+	}
+
+	buf := new(bytes.Buffer)
+	writeMetrics(buf, stats, procs, 2)
+	out := buf.String()
+
+	assert.Contains(t, out, `pgcacher_file_cached_bytes{file="/data/a"} 100`)
+	assert.Contains(t, out, `pgcacher_file_cached_bytes{file="/data/b\"x"} 100`)
+	assert.NotContains(t, out, `file="/logs/c"`)
+	assert.Contains(t, out, `pgcacher_process_cached_bytes{pid="1",executable="init"} 110`)
+	assert.Contains(t, out, `pgcacher_dir_size_bytes{dir="/data"} 300`)
+	assert.Contains(t, out, `pgcacher_dir_cached_percent{dir="/logs"} 10`)
+}
+
+func TestLoadReport(t *testing.T) {
+	f, err := os.CreateTemp("", "pgcacher-report")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+
+	// two samples of -interval -json, then a line of -ndjson.
+	f.WriteString(`[{"filename":"a","cached":1}]` + "\n")
+	f.WriteString(`[{"filename":"a","cached":2},{"filename":"b","cached":3}]` + "\n")
+	f.WriteString(`{"filename":"c","cached":4}` + "\n")
+	f.Close()
+
+	stats, err := loadReport(f.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(stats))
+	assert.Equal(t, 2, stats[0].Cached)
+	assert.Equal(t, "c", stats[2].Name)
+}
+
+func TestFormatHolders(t *testing.T) {
+	holders := []pcstats.Holder{
+		{Pid: 1, Executable: "init"},
+		{Pid: 2, Executable: "a"},
+		{Pid: 3, Executable: "b"},
+		{Pid: 4, Executable: "c"},
+		{Pid: 5, Executable: "d"},
+	}
+	assert.Equal(t, "", formatHolders(nil))
+	assert.Equal(t, "init(1)", formatHolders(holders[:1]))
+	assert.Equal(t, "init(1), a(2), b(3), +2 more", formatHolders(holders))
+}
+
+func TestProcessStatList(t *testing.T) {
+	stats := PcStatusList{
+		{Name: "/lib/libc.so", Size: 100, Percent: 100},
+		{Name: "/data/a", Size: 400, Percent: 50},
+	}
+	procs := []processFiles{
+		{pid: 1, exe: "init", files: []string{"/lib/libc.so"}},
+		{pid: 2, exe: "db", rss: 2, files: []string{"/lib/libc.so", "/data/a", "/data/a", "/filtered"}},
+	}
+
+	list := newProcessStatList(stats, procs)
+	assert.Equal(t, 2, len(list))
+
+	db := list[0]
+	assert.Equal(t, 2, db.Pid)
+	assert.Equal(t, 2, db.Files)
+	assert.Equal(t, 1, db.Shared)
+	assert.Equal(t, int64(500), db.Size)
+	assert.Equal(t, int64(300), db.Cached)
+	assert.Equal(t, int64(250), db.Apportioned)
+	assert.Equal(t, int64(2*os.Getpagesize()), db.RSS)
+
+	assert.Equal(t, int64(50), list[1].Apportioned)
+}
+
+func TestGroupByDir(t *testing.T) {
+	assert.Equal(t, "/var/lib/kafka/t0", groupDir("/var/lib/kafka/t0/00.log", 0))
+	assert.Equal(t, "/var/lib", groupDir("/var/lib/kafka/t0/00.log", 2))
+	assert.Equal(t, "/var/lib/kafka/t0", groupDir("/var/lib/kafka/t0/00.log", 10))
+	assert.Equal(t, "data", groupDir("data/a/b", 1))
+	assert.Equal(t, ".", groupDir("a", 1))
+
+	depth, err := parseGroupBy("dir:3")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, depth)
+	_, err = parseGroupBy("file")
+	assert.NotNil(t, err)
+
+	groups := groupByDir(PcStatusList{
+		{Name: "/k/t0/a", Size: 10, Pages: 10, Cached: 1},
+		{Name: "/k/t1/a", Size: 10, Pages: 10, Cached: 5},
+		{Name: "/k/t1/b", Size: 10, Pages: 10, Cached: 5},
+	}, 2)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, "/k/t1", groups[0].Dir)
+	assert.Equal(t, 2, groups[0].Files)
+	assert.Equal(t, int64(10), groups[0].Cached)
+	assert.Equal(t, 50.0, groups[0].Percent)
+}
+
+func TestProcessSelector(t *testing.T) {
+	proc, err := psutils.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Equal(t, os.Getuid(), proc.Uid())
+	assert.Equal(t, os.Args, proc.Cmdline())
+	assert.True(t, time.Since(proc.StartTime()) < time.Hour)
+
+	sel, err := newProcessSelector(proc.Executable()+",nginx", regexp.QuoteMeta(os.Args[0]), strconv.Itoa(os.Getuid()))
+	assert.Nil(t, err)
+	assert.True(t, sel.match(proc))
+
+	sel, err = newProcessSelector("nginx", "", "")
+	assert.Nil(t, err)
+	assert.False(t, sel.match(proc))
+
+	sel, err = newProcessSelector("", "^no-such-command", "")
+	assert.Nil(t, err)
+	assert.False(t, sel.match(proc))
+
+	_, err = newProcessSelector("", "(", "")
+	assert.NotNil(t, err)
+}
+
+func TestDescendants(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	assert.Nil(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	procs, err := psutils.Processes()
+	assert.Nil(t, err)
+
+	var pids []int
+	for _, proc := range psutils.Descendants(os.Getpid(), procs) {
+		pids = append(pids, proc.Pid())
+	}
+	assert.Contains(t, pids, cmd.Process.Pid)
+	assert.Empty(t, psutils.Descendants(cmd.Process.Pid, procs))
+}
+
+func TestMountNsFiles(t *testing.T) {
+	pg := pgcacher{option: &option{}, selfNs: 1}
+	pg.addProcessFiles(processFiles{pid: 1, exe: "init", files: []string{"/lib/libc.so"}}, 1)
+	pg.addProcessFiles(processFiles{pid: 2, exe: "nginx", files: []string{"/lib/libc.so"}}, 2)
+	pg.addProcessFiles(processFiles{pid: 3, exe: "nginx", files: []string{"/lib/libc.so", "/var/log/a"}}, 2)
+	pg.addProcessFiles(processFiles{pid: 4, exe: "unknown", files: []string{"/data/b"}}, 0)
+	pg.filterFiles()
+
+	assert.ElementsMatch(t, []string{"/lib/libc.so", "/data/b"}, pg.files)
+	assert.Equal(t, 1, len(pg.nsFiles))
+	assert.Equal(t, "mnt:[2]", pg.nsFiles[0].label)
+	assert.Equal(t, []int{2, 3}, pg.nsFiles[0].pids)
+	assert.ElementsMatch(t, []string{"/lib/libc.so", "/var/log/a"}, pg.nsFiles[0].files)
+	assert.True(t, pg.hasFiles())
+
+	// the same path in two namespaces is held by different processes.
+	stats := PcStatusList{
+		{Name: "/lib/libc.so"},
+		{Name: "/lib/libc.so", MountNs: "mnt:[2]"},
+	}
+	pg.attachProcesses(stats)
+	assert.Equal(t, []pcstats.Holder{{Pid: 1, Executable: "init"}}, stats[0].Processes)
+	assert.Equal(t, []pcstats.Holder{{Pid: 2, Executable: "nginx"}, {Pid: 3, Executable: "nginx"}}, stats[1].Processes)
+}
+
+func TestDeletedFiles(t *testing.T) {
+	f, err := os.CreateTemp("", "pgcacher")
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = f.Write(make([]byte, 3*os.Getpagesize()))
+	assert.Nil(t, err)
+	assert.Nil(t, os.Remove(f.Name()))
+
+	pg := pgcacher{option: &option{worker: 2}}
+	files, links := pg.getProcessFiles(os.Getpid())
+	fname := f.Name() + deletedSuffix
+	assert.Contains(t, files, fname)
+	assert.Contains(t, links, fname)
+
+	pg.addProcessFiles(processFiles{pid: os.Getpid(), files: files, links: links}, 0)
+	assert.NotContains(t, pg.files, fname)
+
+	stats := pg.measureHeldFiles()
+	var found bool
+	for _, pcs := range stats {
+		if pcs.Name == fname {
+			found = true
+			assert.True(t, pcs.Deleted)
+			assert.Equal(t, 3, pcs.Pages)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestParseMapsLine(t *testing.T) {
+	addr, fname, ok := parseMapsLine("7f00-7f10 r--p 00000000 fd:01 1234  /usr/lib/libc.so.6")
+	assert.True(t, ok)
+	assert.Equal(t, "7f00-7f10", addr)
+	assert.Equal(t, "/usr/lib/libc.so.6", fname)
+
+	_, fname, ok = parseMapsLine("7f00-7f10 rw-s 00000000 fd:01 1234  /tmp/my log (deleted)")
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/my log (deleted)", fname)
+
+	_, fname, ok = parseMapsLine("7f00-7f10 rw-s 00000000 00:01 9012  /memfd:pool (deleted)")
+	assert.True(t, ok)
+	assert.True(t, isDeleted(fname))
+
+	for _, line := range []string{
+		"7f00-7f10 rw-s 00000000 00:01 5678  /dev/zero (deleted)",
+		"7f00-7f10 rw-s 00000000 00:01 32768  /SYSV00000000 (deleted)",
+		"7f00-7f10 rw-p 00000000 00:00 0  [heap]",
+		"7f00-7f10 rw-p 00000000 00:00 0",
+	} {
+		_, _, ok = parseMapsLine(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestDedupFiles(t *testing.T) {
+	stats := PcStatusList{
+		{Name: "/srv/current/app.jar", Dev: 1, Inode: 10, Cached: 5, Processes: []pcstats.Holder{{Pid: 3}}},
+		{Name: "/srv/v1/app.jar", Dev: 1, Inode: 10, Cached: 5, Processes: []pcstats.Holder{{Pid: 2}, {Pid: 3}}},
+		{Name: "/srv/v1/app.jar", Dev: 1, Inode: 10, Cached: 5, MountNs: "mnt:[2]"},
+		{Name: "/srv/v1/other.jar", Dev: 2, Inode: 10, Cached: 1},
+		{Name: "/srv/v1/other.jar", Dev: 2, Inode: 10, Cached: 1},
+		{Name: "/proc-less", Cached: 1},
+		{Name: "/proc-less2", Cached: 1},
+	}
+
+	list := dedupFiles(stats)
+	assert.Equal(t, 4, len(list))
+	assert.Equal(t, "/srv/v1/app.jar", list[0].Name)
+	assert.Equal(t, []string{"/srv/current/app.jar", "mnt:[2] /srv/v1/app.jar"}, list[0].Aliases)
+	assert.Equal(t, []pcstats.Holder{{Pid: 2}, {Pid: 3}}, list[0].Processes)
+	assert.Equal(t, "/srv/v1/other.jar", list[1].Name)
+	assert.Empty(t, list[1].Aliases)
+}
+
+func TestTopStats(t *testing.T) {
+	top := &topStats{limit: 3}
+	for _, cached := range []int{5, 1, 9, 3, 7, 2} {
+		top.add(pcstats.PcStatus{Name: strconv.Itoa(cached), Cached: cached})
+	}
+
+	stats := top.stats
+	sort.Sort(stats)
+	assert.Equal(t, 3, len(stats))
+	assert.Equal(t, []int{9, 7, 5}, []int{stats[0].Cached, stats[1].Cached, stats[2].Cached})
+
+	top = &topStats{limit: 2}
+	top.add(pcstats.PcStatus{Name: "/a/long", Dev: 1, Inode: 1, Cached: 9})
+	top.add(pcstats.PcStatus{Name: "/b", Dev: 1, Inode: 2, Cached: 1})
+	top.add(pcstats.PcStatus{Name: "/a/l", Dev: 1, Inode: 1, Cached: 9})
+	top.add(pcstats.PcStatus{Name: "/c", Dev: 1, Inode: 3, Cached: 5})
+	top.add(pcstats.PcStatus{Name: "/mnt/c", Dev: 1, Inode: 3, Cached: 5})
+
+	stats = top.stats
+	sort.Sort(stats)
+	assert.Equal(t, []string{"/a/l", "/c"}, []string{stats[0].Name, stats[1].Name})
+	assert.Equal(t, []string{"/a/long"}, stats[0].Aliases)
+	assert.Equal(t, []string{"/mnt/c"}, stats[1].Aliases)
+}
+
+func TestDedupRoots(t *testing.T) {
+	roots := []scanRoot{
+		{dir: "/data", major: 8, minor: 2, fsPath: "/"},
+		{dir: "/var/log", major: 8, minor: 2, fsPath: "/logs"},
+		{dir: "/srv/data", major: 8, minor: 2, fsPath: "/"},
+		{dir: "/home", major: 8, minor: 3, fsPath: "/home"},
+		{dir: "/mnt/home", major: 8, minor: 3, fsPath: "/home/alice"},
+		{dir: "/mnt/other", major: 8, minor: 3, fsPath: "/homes"},
+		{dir: "/unknown"},
+	}
+	assert.Equal(t, []string{"/data", "/home", "/mnt/other", "/unknown"}, dedupRoots(roots))
+}
+
+func TestFindMount(t *testing.T) {
+	mounts := []pcstats.Mount{
+		{MountPoint: "/", FSType: "ext4"},
+		{MountPoint: "/data", FSType: "xfs"},
+		{MountPoint: "/data", FSType: "tmpfs"},
+		{MountPoint: "/proc", FSType: "proc"},
+	}
+
+	m, ok := pcstats.FindMount(mounts, "/data/kafka")
+	assert.True(t, ok)
+	assert.Equal(t, "tmpfs", m.FSType)
+
+	m, _ = pcstats.FindMount(mounts, "/database")
+	assert.Equal(t, "ext4", m.FSType)
+	m, _ = pcstats.FindMount(mounts, "/proc")
+	assert.True(t, pcstats.IsPseudoFS(m.FSType))
+
+	all, err := pcstats.ReadMounts()
+	assert.Nil(t, err)
+	_, ok = pcstats.FindMount(all, "/")
+	assert.True(t, ok)
+}
+
+func TestGroupByMounts(t *testing.T) {
+	mounts := []pcstats.Mount{
+		{ID: 1, Major: 8, Minor: 1, MountPoint: "/", FSType: "ext4", Source: "/dev/sda1"},
+		{ID: 2, Major: 8, Minor: 2, MountPoint: "/data", FSType: "xfs", Source: "/dev/sda2"},
+		{ID: 3, Major: 8, Minor: 2, Root: "/logs", MountPoint: "/var/log", FSType: "xfs", Source: "/dev/sda2"},
+	}
+	stats := PcStatusList{
+		{Name: "/data/a", Dev: unix.Mkdev(8, 2), Size: 40960, Pages: 10, Cached: 8},
+		{Name: "/var/log/b", Dev: unix.Mkdev(8, 2), Size: 40960, Pages: 10, Cached: 2},
+		{Name: "/usr/lib/c", Dev: unix.Mkdev(8, 1), Size: 40960, Pages: 10, Cached: 5},
+		{Name: "/memfd:x (deleted)", Dev: unix.Mkdev(0, 1), Pages: 1, Cached: 1},
+	}
+
+	list := groupByMounts(stats, mounts, false)
+	assert.Equal(t, 4, len(list.stats))
+	assert.Equal(t, "/data", list.stats[0].MountPoint)
+	assert.Equal(t, "/usr/lib/c", list.stats[1].stats[0].Name)
+	assert.Equal(t, "/var/log", list.stats[2].MountPoint)
+	assert.Equal(t, unknownMount, list.stats[3].MountPoint)
+
+	list = groupByMounts(stats, mounts, true)
+	assert.Equal(t, 3, len(list.stats))
+	assert.Equal(t, "xfs", list.stats[0].FSType)
+	assert.Equal(t, 2, list.stats[0].Mounts)
+	assert.Equal(t, 2, list.stats[0].Files)
+	assert.Equal(t, int64(10), list.stats[0].Cached)
+	assert.Equal(t, 50.0, list.stats[0].Percent)
+
+	assert.False(t, isShmemMount(mounts[0]))
+	assert.True(t, isShmemMount(pcstats.Mount{MountPoint: "/dev", FSType: "devtmpfs"}))
+	assert.True(t, isShmemMount(pcstats.Mount{MountPoint: "/dev/shm", FSType: "tmpfs"}))
+	assert.True(t, isShmemMount(pcstats.Mount{MountPoint: "/run/shm", FSType: "other", Source: "shm"}))
+}
+
+func TestReadMeminfo(t *testing.T) {
+	if _, err := os.Stat("/proc/meminfo"); err != nil {
+		t.Skip("no /proc/meminfo")
+	}
+
+	info, err := pcstats.ReadMeminfo()
+	assert.Nil(t, err)
+	assert.True(t, info.Cached > 0)
+}
+
+func TestDirWalker(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(root+"/a/b", 0755))
+	for _, fname := range []string{"/top", "/a/mid", "/a/b/deep", "/a/b/skip.log"} {
+		assert.Nil(t, os.WriteFile(root+fname, []byte("x"), 0644))
+	}
+	assert.Nil(t, os.Symlink(root, root+"/a/loop"))
+	assert.Nil(t, os.Symlink(root+"/top", root+"/link"))
+
+	walk := func(maxDepth int, followSymlinks bool) []string {
+		out := make(chan string, 100)
+		ignore := func(fname string) bool { return wildcardMatch(fname, "*.log") }
+		newDirWalker(4, maxDepth, followSymlinks, false, ignore).walk([]string{root}, out)
+		close(out)
+
+		var files []string
+		for fname := range out {
+			files = append(files, strings.TrimPrefix(fname, root))
+		}
+		return files
+	}
+
+	assert.ElementsMatch(t, []string{"/top", "/a/mid", "/a/b/deep"}, walk(0, false))
+	assert.ElementsMatch(t, []string{"/top", "/a/mid"}, walk(2, false))
+	assert.ElementsMatch(t, []string{"/top", "/link", "/a/mid", "/a/b/deep"}, walk(0, true))
+
+	// the subdirs on a pseudo filesystem are skipped, the root is walked.
+	finfo, err := os.Stat(root)
+	assert.Nil(t, err)
+	id, _ := statID(finfo)
+	walker := newDirWalker(1, 0, false, false, nil)
+	walker.pseudoDevs = map[uint64]emptyNull{id.dev: {}}
+	out := make(chan string, 100)
+	walker.walk([]string{root}, out)
+	close(out)
+	assert.Equal(t, root+"/top", <-out)
+	assert.Equal(t, 0, len(out))
+
+	files, dirs := splitDirs([]string{root, root + "/top", root + "/missing"})
+	assert.Equal(t, []string{root}, dirs)
+	assert.Equal(t, []string{root + "/top", root + "/missing"}, files)
+
+	files, dirs = pruneCovered([]string{root + "/top", root + "/a/mid", root + "/missing"}, []string{root + "/a", root, root + "/"}, 0)
+	assert.Equal(t, []string{root}, dirs)
+	assert.Equal(t, []string{root + "/missing"}, files)
+
+	files, dirs = pruneCovered([]string{root + "/top", root + "/a/mid"}, []string{root, root + "/a"}, 1)
+	assert.Equal(t, []string{root, root + "/a"}, dirs)
+	assert.Equal(t, []string{root + "/a/mid"}, files)
+}
@@ -0,0 +1,10 @@
+package pcstats
+
+// Cachestat is the page cache state of the file returned by cachestat(2).
+type Cachestat struct {
+	Cached          int64
+	Dirty           int64
+	Writeback       int64
+	Evicted         int64
+	RecentlyEvicted int64
+}
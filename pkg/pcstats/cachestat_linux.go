@@ -0,0 +1,78 @@
+package pcstats
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// cachestat(2) was added in linux 6.5, sysCachestat is defined per
+// architecture in cachestat_linux_*.go.
+
+// struct cachestat_range
+type cachestatRange struct {
+	Off uint64
+	Len uint64
+}
+
+// struct cachestat
+type cachestat struct {
+	Cache           uint64
+	Dirty           uint64
+	Writeback       uint64
+	Evicted         uint64
+	RecentlyEvicted uint64
+}
+
+// cachestatUnsupported is set once the kernel told us it doesn't know
+// cachestat(2), so we don't pay for a failing syscall on every file.
+var cachestatUnsupported int32
+
+// GetFileCachestat queries the page cache state of the whole file with
+// cachestat(2). ok is false when the running kernel doesn't support it,
+// the caller should fall back to mincore.
+func GetFileCachestat(f *os.File) (value *Cachestat, ok bool, err error) {
+	if atomic.LoadInt32(&cachestatUnsupported) == 1 {
+		return nil, false, nil
+	}
+
+	// len 0 means from off to the end of the file.
+	crange := cachestatRange{Off: 0, Len: 0}
+	cstat := cachestat{}
+
+	_, _, errno := unix.Syscall6(
+		sysCachestat,
+		f.Fd(),
+		uintptr(unsafe.Pointer(&crange)),
+		uintptr(unsafe.Pointer(&cstat)),
+		0, 0, 0,
+	)
+	switch errno {
+	case 0:
+	case unix.ENOSYS:
+		atomic.StoreInt32(&cachestatUnsupported, 1)
+		return nil, false, nil
+	case unix.EPERM:
+		// seccomp filters of some container runtimes reject unknown
+		// syscalls with EPERM, treat it like an old kernel.
+		atomic.StoreInt32(&cachestatUnsupported, 1)
+		return nil, false, nil
+	case unix.EOPNOTSUPP:
+		// hugetlbfs files, only this file needs the fallback.
+		return nil, false, nil
+	default:
+		return nil, true, fmt.Errorf("syscall SYS_CACHESTAT failed: %v", errno)
+	}
+
+	value = &Cachestat{
+		Cached:          int64(cstat.Cache),
+		Dirty:           int64(cstat.Dirty),
+		Writeback:       int64(cstat.Writeback),
+		Evicted:         int64(cstat.Evicted),
+		RecentlyEvicted: int64(cstat.RecentlyEvicted),
+	}
+	return value, true, nil
+}
@@ -0,0 +1,8 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package pcstats
+
+// syscalls added since linux 5.1 share their numbers on all architectures
+// but the mips abis, which offset them.
+const sysCachestat = 451
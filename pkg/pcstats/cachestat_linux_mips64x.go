@@ -0,0 +1,8 @@
+//go:build linux && (mips64 || mips64le)
+// +build linux
+// +build mips64 mips64le
+
+package pcstats
+
+// the n64 abi of mips64 numbers the syscalls from 5000.
+const sysCachestat = 5451
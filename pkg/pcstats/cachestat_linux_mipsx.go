@@ -0,0 +1,8 @@
+//go:build linux && (mips || mipsle)
+// +build linux
+// +build mips mipsle
+
+package pcstats
+
+// the o32 abi of mips numbers the syscalls from 4000.
+const sysCachestat = 4451
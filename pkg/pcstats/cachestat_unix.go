@@ -0,0 +1,13 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+
+package pcstats
+
+import (
+	"os"
+)
+
+// GetFileCachestat is only supported on linux, always fall back to mincore.
+func GetFileCachestat(f *os.File) (*Cachestat, bool, error) {
+	return nil, false, nil
+}
@@ -0,0 +1,26 @@
+package pcstats
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// EvictFile drops the clean pages of the file from the page cache with
+// posix_fadvise(POSIX_FADV_DONTNEED). dirty pages stay in the cache until
+// they have been written back.
+func EvictFile(fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return fmt.Errorf("could not open file for read: %v", err)
+	}
+	defer f.Close()
+
+	// offset 0 and len 0 means the whole file.
+	if err := unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED); err != nil {
+		return fmt.Errorf("posix_fadvise DONTNEED failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+
+package pcstats
+
+import (
+	"errors"
+)
+
+var errNotSupported = errors.New("not supported on this platform")
+
+func EvictFile(fname string) error {
+	return errNotSupported
+}
@@ -0,0 +1,49 @@
+package pcstats
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Meminfo is the page cache of the whole system in /proc/meminfo, in bytes.
+type Meminfo struct {
+	Cached  int64 `json:"cached"`  // page cache of files, including Shmem
+	Buffers int64 `json:"buffers"` // page cache of block devices, such as filesystem metadata
+	Shmem   int64 `json:"shmem"`   // tmpfs, shared memory and memfd
+}
+
+func ReadMeminfo() (Meminfo, error) {
+	var info Meminfo
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// such as "Cached:          1234567 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "Cached:":
+			info.Cached = kb << 10
+		case "Buffers:":
+			info.Buffers = kb << 10
+		case "Shmem:":
+			info.Shmem = kb << 10
+		}
+	}
+
+	return info, scanner.Err()
+}
@@ -27,53 +27,129 @@ import (
 type Mincore struct {
 	Cached int64
 	Miss   int64
+	Ranges []PageRange // only filled when asked for
 }
 
-// mmap the given file, get the mincore vector, then
-// return it as an []bool
+// PageRange is a run of consecutive cached pages, [Start, End) in
+// page index of the file.
+type PageRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// WindowSize is the max length of the file mapped at once. Huge files are
+// scanned window by window, so neither the mapping nor the mincore vector
+// grows with the size of the file.
+var WindowSize int64 = 1 << 30
+
+// mmap the given file window by window, get the mincore vector of
+// each window, then count the cached and missed pages.
 func GetFileMincore(f *os.File, size int64) (*Mincore, error) {
+	return getFileMincore(f, size, false)
+}
+
+// getFileMincore is GetFileMincore, it also collects the ranges of cached
+// pages when keepRanges is set.
+func getFileMincore(f *os.File, size int64, keepRanges bool) (*Mincore, error) {
 	//skip could not mmap error when the file size is 0
 	if int(size) == 0 {
 		return nil, nil
 	}
+
+	value := new(Mincore)
+	err := walkFileMincore(f, size, func(vec []byte, page int64) {
+		for i, b := range vec {
+			if b%2 == 0 {
+				value.Miss++
+				continue
+			}
+
+			value.Cached++
+			if !keepRanges {
+				continue
+			}
+
+			// extend the last range when the page follows it, also
+			// across windows.
+			cur := page + int64(i)
+			if n := len(value.Ranges); n > 0 && value.Ranges[n-1].End == cur {
+				value.Ranges[n-1].End++
+				continue
+			}
+			value.Ranges = append(value.Ranges, PageRange{Start: cur, End: cur + 1})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// walkFileMincore calls fn with the mincore vector of every window of the
+// file, page is the index of the first page of the window in the file.
+// the vector is reused across windows, fn must not retain it.
+func walkFileMincore(f *os.File, size int64, fn func(vec []byte, page int64)) error {
+	pageSize := int64(os.Getpagesize())
+
+	// mmap offsets must be a multiple of the page size.
+	window := WindowSize / pageSize * pageSize
+	if window <= 0 {
+		window = pageSize
+	}
+
+	vec := make([]byte, (min64(window, size)+pageSize-1)/pageSize)
+	for off := int64(0); off < size; off += window {
+		length := min64(window, size-off)
+		n, err := mincoreWindow(f, off, length, vec)
+		if err != nil {
+			return err
+		}
+		fn(vec[:n], off/pageSize)
+	}
+
+	return nil
+}
+
+// mincoreWindow maps length bytes of the file at off, fills vec with the
+// mincore result and unmaps it again. returns the number of pages in vec.
+func mincoreWindow(f *os.File, off, length int64, vec []byte) (int, error) {
 	// mmap is a []byte
-	mmap, err := unix.Mmap(int(f.Fd()), 0, int(size), unix.PROT_NONE, unix.MAP_SHARED)
+	mmap, err := unix.Mmap(int(f.Fd()), off, int(length), unix.PROT_NONE, unix.MAP_SHARED)
 	if err != nil {
-		return nil, fmt.Errorf("could not mmap: %v", err)
+		return 0, fmt.Errorf("could not mmap: %v", err)
 	}
+	defer unix.Munmap(mmap)
 	// TODO: check for MAP_FAILED which is ((void *) -1)
 	// but maybe unnecessary since it looks like errno is always set when MAP_FAILED
 
 	// one byte per page, only LSB is used, remainder is reserved and clear
-	vecsz := (size + int64(os.Getpagesize()) - 1) / int64(os.Getpagesize())
-	vec := make([]byte, vecsz)
+	pageSize := int64(os.Getpagesize())
+	vecsz := (length + pageSize - 1) / pageSize
 
 	// get all of the arguments to the mincore syscall converted to uintptr
 	mmap_ptr := uintptr(unsafe.Pointer(&mmap[0]))
-	size_ptr := uintptr(size)
+	size_ptr := uintptr(length)
 	vec_ptr := uintptr(unsafe.Pointer(&vec[0]))
 
 	// use Go's ASM to submit directly to the kernel, no C wrapper needed
 	// mincore(2): int mincore(void *addr, size_t length, unsigned char *vec);
 	// 0 on success, takes the pointer to the mmap, a size, which is the
-	// size that came from f.Stat(), and the vector, which is a pointer
+	// length of the window, and the vector, which is a pointer
 	// to the memory behind an []byte
 	// this writes a snapshot of the data into vec which a list of 8-bit flags
 	// with the LSB set if the page in that position is currently in VFS cache
 	ret, _, err := unix.Syscall(unix.SYS_MINCORE, mmap_ptr, size_ptr, vec_ptr)
 	if ret != 0 {
-		return nil, fmt.Errorf("syscall SYS_MINCORE failed: %v", err)
+		return 0, fmt.Errorf("syscall SYS_MINCORE failed: %v", err)
 	}
-	defer unix.Munmap(mmap)
 
-	value := new(Mincore)
-	for _, b := range vec {
-		if b%2 == 1 {
-			value.Cached++
-		} else {
-			value.Miss++
-		}
-	}
+	return int(vecsz), nil
+}
 
-	return value, nil
+func min64(x, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
 }
@@ -0,0 +1,34 @@
+package pcstats
+
+// LockedFile is a file mapped and locked into memory with mlock(2), the
+// pages stay resident until Unlock is called or the process exits.
+type LockedFile struct {
+	Name   string
+	Locked int64 // number of bytes locked
+
+	mmaps [][]byte
+}
+
+// LockLength returns the bytes LockFile locks for the given ranges, the
+// whole file when ranges is nil.
+func LockLength(size int64, ranges []PageRange, pageSize int64) int64 {
+	if ranges == nil {
+		return size
+	}
+
+	var total int64
+	for _, r := range ranges {
+		if _, length := lockRange(r, size, pageSize); length > 0 {
+			total += length
+		}
+	}
+	return total
+}
+
+// lockRange returns the offset and length of the page range in the file,
+// the last page is cut at the end of the file. the length is not positive
+// when the range is beyond the end of the file.
+func lockRange(r PageRange, size, pageSize int64) (int64, int64) {
+	off := r.Start * pageSize
+	return off, min64((r.End-r.Start)*pageSize, size-off)
+}
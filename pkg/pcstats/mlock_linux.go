@@ -0,0 +1,68 @@
+package pcstats
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// LockFile maps the given page ranges of the file and locks them into
+// memory, the whole file is locked when ranges is nil.
+func LockFile(fname string, ranges []PageRange) (*LockedFile, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file for read: %v", err)
+	}
+	// the mappings keep the file referenced, it can be closed right away.
+	defer f.Close()
+
+	finfo, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat file: %v", err)
+	}
+
+	pageSize := int64(os.Getpagesize())
+	if ranges == nil {
+		ranges = []PageRange{{Start: 0, End: (finfo.Size() + pageSize - 1) / pageSize}}
+	}
+
+	lf := &LockedFile{Name: fname}
+	for _, r := range ranges {
+		off, length := lockRange(r, finfo.Size(), pageSize)
+		if length <= 0 {
+			continue
+		}
+
+		mmap, err := unix.Mmap(int(f.Fd()), off, int(length), unix.PROT_READ, unix.MAP_SHARED)
+		if err != nil {
+			lf.Unlock()
+			return nil, fmt.Errorf("could not mmap: %v", err)
+		}
+		lf.mmaps = append(lf.mmaps, mmap)
+
+		if err := unix.Mlock(mmap); err != nil {
+			lf.Unlock()
+			return nil, fmt.Errorf("could not mlock: %v", err)
+		}
+		lf.Locked += length
+	}
+
+	return lf, nil
+}
+
+// Unlock unlocks and unmaps all locked ranges of the file.
+func (lf *LockedFile) Unlock() error {
+	var lastErr error
+	for _, mmap := range lf.mmaps {
+		if err := unix.Munlock(mmap); err != nil {
+			lastErr = err
+		}
+		if err := unix.Munmap(mmap); err != nil {
+			lastErr = err
+		}
+	}
+	lf.mmaps = nil
+	lf.Locked = 0
+	return lastErr
+}
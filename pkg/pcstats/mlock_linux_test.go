@@ -0,0 +1,38 @@
+package pcstats
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestLockFile(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+	size := 3*pageSize + 100
+
+	var rlim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &rlim); err != nil || rlim.Cur != unix.RLIM_INFINITY && rlim.Cur < uint64(size) {
+		t.Skip("RLIMIT_MEMLOCK is too small")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "pgcacher")
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = f.Write(make([]byte, size))
+	assert.Nil(t, err)
+
+	lf, err := LockFile(f.Name(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, size, lf.Locked)
+	assert.Nil(t, lf.Unlock())
+	assert.Equal(t, int64(0), lf.Locked)
+
+	ranges := []PageRange{{Start: 1, End: 2}, {Start: 3, End: 5}}
+	lf, err = LockFile(f.Name(), ranges)
+	assert.Nil(t, err)
+	assert.Equal(t, LockLength(size, ranges, pageSize), lf.Locked)
+	assert.Equal(t, pageSize+100, lf.Locked)
+	assert.Nil(t, lf.Unlock())
+}
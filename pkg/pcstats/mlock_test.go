@@ -0,0 +1,45 @@
+package pcstats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockLength(t *testing.T) {
+	cases := []struct {
+		size   int64
+		ranges []PageRange
+		want   int64
+	}{
+		{size: 10000, ranges: nil, want: 10000},
+		{size: 10000, ranges: []PageRange{}, want: 0},
+		{size: 10000, ranges: []PageRange{{Start: 0, End: 1}}, want: 4096},
+		{size: 10000, ranges: []PageRange{{Start: 0, End: 3}}, want: 10000},
+		{size: 10000, ranges: []PageRange{{Start: 0, End: 1}, {Start: 2, End: 3}}, want: 4096 + 1808},
+		{size: 10000, ranges: []PageRange{{Start: 2, End: 3}}, want: 1808},
+		{size: 10000, ranges: []PageRange{{Start: 3, End: 5}}, want: 0},
+		{size: 8192, ranges: []PageRange{{Start: 1, End: 2}}, want: 4096},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, LockLength(c.size, c.ranges, 4096), "%d %v", c.size, c.ranges)
+	}
+}
+
+func TestLockRange(t *testing.T) {
+	cases := []struct {
+		r           PageRange
+		size        int64
+		off, length int64
+	}{
+		{r: PageRange{Start: 0, End: 2}, size: 10000, off: 0, length: 8192},
+		{r: PageRange{Start: 1, End: 3}, size: 10000, off: 4096, length: 5904},
+		{r: PageRange{Start: 2, End: 3}, size: 8192, off: 8192, length: 0},
+		{r: PageRange{Start: 4, End: 5}, size: 8192, off: 16384, length: -8192},
+	}
+	for _, c := range cases {
+		off, length := lockRange(c.r, c.size, 4096)
+		assert.Equal(t, c.off, off, "%v", c.r)
+		assert.Equal(t, c.length, length, "%v", c.r)
+	}
+}
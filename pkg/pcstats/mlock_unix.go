@@ -0,0 +1,12 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+
+package pcstats
+
+func LockFile(fname string, ranges []PageRange) (*LockedFile, error) {
+	return nil, errNotSupported
+}
+
+func (lf *LockedFile) Unlock() error {
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
 
@@ -30,18 +31,59 @@ import (
 const CLONE_NEWNS = 0x00020000 /* mount namespace */
 
 // if the pid is in a different mount namespace (e.g. Docker)
-// the paths will be all wrong, so try to enter that namespace
+// the paths will be all wrong, so try to enter that namespace.
+// the calling goroutine is locked to its thread for good, the other
+// threads stay in the original namespace.
+//
+// Deprecated: use EnterMountNs, which reports the error.
 func SwitchMountNs(pid int) {
-	myns := getMountNs(os.Getpid())
-	pidns := getMountNs(pid)
+	runtime.LockOSThread()
+	if err := EnterMountNs(pid); err != nil {
+		log.Printf("failed to enter the mount namespace of pid %d: %v", pid, err)
+	}
+}
+
+// MountNs returns the inode of the mount namespace of the pid, or 0 when
+// it's unknown, e.g. the process has exited.
+func MountNs(pid int) int {
+	return getMountNs(pid)
+}
+
+// EnterMountNs makes the calling OS thread enter the mount namespace of
+// the pid. the goroutine must be locked to its thread with
+// runtime.LockOSThread and must never unlock it, so the thread is thrown
+// away when the goroutine exits instead of serving other goroutines.
+func EnterMountNs(pid int) error {
+	// the namespace of the process is the one of its main thread, which
+	// may have entered another namespace already.
+	self := getMountNsOf(fmt.Sprintf("/proc/%d/task/%d/ns/mnt", os.Getpid(), unix.Gettid()))
+	if getMountNs(pid) == self {
+		return nil
+	}
 
-	if myns != pidns {
-		setns(pidns)
+	f, err := os.Open(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+	if err != nil {
+		return fmt.Errorf("could not open mount namespace: %v", err)
+	}
+	defer f.Close()
+
+	// threads of a process share the root and cwd, the kernel refuses to
+	// switch the mount namespace of a thread until it has its own.
+	if err := unix.Unshare(unix.CLONE_FS); err != nil {
+		return fmt.Errorf("syscall SYS_UNSHARE failed: %v", err)
+	}
+	if err := unix.Setns(int(f.Fd()), unix.CLONE_NEWNS); err != nil {
+		return fmt.Errorf("syscall SYS_SETNS failed: %v", err)
 	}
+
+	return nil
 }
 
 func getMountNs(pid int) int {
-	fname := fmt.Sprintf("/proc/%d/ns/mnt", pid)
+	return getMountNsOf(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+}
+
+func getMountNsOf(fname string) int {
 	nss, err := os.Readlink(fname)
 
 	// probably permission denied or namespaces not compiled into the kernel
@@ -61,12 +103,3 @@ func getMountNs(pid int) int {
 
 	return ns
 }
-
-func setns(fd int) error {
-	ret, _, err := unix.Syscall(unix.SYS_SETNS, uintptr(uint(fd)), uintptr(CLONE_NEWNS), 0)
-	if ret != 0 {
-		return fmt.Errorf("syscall SYS_SETNS failed: %v", err)
-	}
-
-	return nil
-}
@@ -22,3 +22,11 @@ package pcstats
 func SwitchMountNs(pid int) {
 	return
 }
+
+func EnterMountNs(pid int) error {
+	return nil
+}
+
+func MountNs(pid int) int {
+	return 0
+}
@@ -0,0 +1,180 @@
+package pcstats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Mount is a line of /proc/self/mountinfo.
+type Mount struct {
+	ID         int    `json:"id"`
+	Major      uint32 `json:"major"`
+	Minor      uint32 `json:"minor"`
+	Root       string `json:"root"`        // the dir of the filesystem mounted, not "/" for bind mounts
+	MountPoint string `json:"mount_point"` // where it's mounted, relative to our root
+	FSType     string `json:"fstype"`
+	Source     string `json:"source"`
+}
+
+// filesystems without files in the page cache, or without files at all.
+var pseudoFSTypes = map[string]bool{
+	"autofs":      true,
+	"binfmt_misc": true,
+	"bpf":         true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"configfs":    true,
+	"debugfs":     true,
+	"devpts":      true,
+	"devtmpfs":    true,
+	"efivarfs":    true,
+	"fusectl":     true,
+	"hugetlbfs":   true,
+	"mqueue":      true,
+	"nsfs":        true,
+	"proc":        true,
+	"pstore":      true,
+	"rpc_pipefs":  true,
+	"securityfs":  true,
+	"selinuxfs":   true,
+	"sysfs":       true,
+	"tracefs":     true,
+}
+
+// IsPseudoFS returns true for the filesystems that are not worth scanning,
+// such as proc, sysfs and cgroup.
+func IsPseudoFS(fstype string) bool {
+	return pseudoFSTypes[fstype]
+}
+
+// ReadMounts returns the mounts in the mount namespace of the calling
+// thread.
+func ReadMounts() ([]Mount, error) {
+	f, err := os.Open("/proc/thread-self/mountinfo")
+	if os.IsNotExist(err) {
+		// linux < 3.17
+		f, err = os.Open("/proc/self/mountinfo")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseMountInfo(f)
+}
+
+// parseMountInfo parses the lines like:
+// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfo(r io.Reader) ([]Mount, error) {
+	var mounts []Mount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		// the optional fields end with a single hyphen.
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 6 || sep < 0 || sep+2 >= len(fields) {
+			return nil, fmt.Errorf("invalid mountinfo line %q", line)
+		}
+
+		var m Mount
+		var err error
+		if m.ID, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid mountinfo line %q", line)
+		}
+		if _, err := fmt.Sscanf(fields[2], "%d:%d", &m.Major, &m.Minor); err != nil {
+			return nil, fmt.Errorf("invalid mountinfo line %q", line)
+		}
+		m.Root = unescapeMountPath(fields[3])
+		m.MountPoint = unescapeMountPath(fields[4])
+		m.FSType = fields[sep+1]
+		m.Source = unescapeMountPath(fields[sep+2])
+		mounts = append(mounts, m)
+	}
+
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes of space, tab, newline and
+// backslash in the paths of mountinfo, such as \040.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// FindMount returns the mount holding the path, which is the last mount on
+// the longest mount point prefixing the path.
+func FindMount(mounts []Mount, path string) (Mount, bool) {
+	var (
+		found Mount
+		ok    bool
+	)
+	for _, m := range mounts {
+		if !hasPathPrefix(path, m.MountPoint) {
+			continue
+		}
+		if !ok || len(m.MountPoint) >= len(found.MountPoint) {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "/" || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
+
+// MountOf returns the mount of the file on the device dev, as st_dev of
+// stat(2). when the device is mounted several times, the mount point which
+// prefixes the path wins.
+func MountOf(mounts []Mount, dev uint64, path string) (Mount, bool) {
+	major, minor := unix.Major(dev), unix.Minor(dev)
+
+	var (
+		found Mount
+		ok    bool
+	)
+	for _, m := range mounts {
+		if m.Major != major || m.Minor != minor {
+			continue
+		}
+		if !ok {
+			found, ok = m, true
+			continue
+		}
+		if hasPathPrefix(path, m.MountPoint) && (!hasPathPrefix(path, found.MountPoint) || len(m.MountPoint) > len(found.MountPoint)) {
+			found = m
+		}
+	}
+	return found, ok
+}
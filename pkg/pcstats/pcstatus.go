@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"syscall"
 	"time"
 )
 
@@ -31,16 +32,53 @@ type PcStatus struct {
 	Size      int64     `json:"size"`      // file size in bytes
 	Timestamp time.Time `json:"timestamp"` // time right before calling mincore
 	Mtime     time.Time `json:"mtime"`     // last modification time of the file
+	Dev       uint64    `json:"dev"`       // device of the file, hardlinks and bind mounts share dev and inode
+	Inode     uint64    `json:"inode"`     // inode of the file
+	Nlink     uint64    `json:"nlink"`     // number of hardlinks of the file
 	Pages     int       `json:"pages"`     // total memory pages
 	Cached    int       `json:"cached"`    // number of pages that are cached
 	Uncached  int       `json:"uncached"`  // number of pages that are not cached
 	Percent   float64   `json:"percent"`   // percentage of pages cached
+
+	// only available when the kernel supports cachestat(2), linux 6.5+
+	Dirty           int `json:"dirty"`            // number of dirty pages
+	Writeback       int `json:"writeback"`        // number of pages marked for writeback
+	Evicted         int `json:"evicted"`          // number of pages evicted from the cache
+	RecentlyEvicted int `json:"recently_evicted"` // number of pages recently evicted from the cache
+
+	// only available when KeepPageRanges is set
+	CachedRanges []PageRange `json:"cached_ranges,omitempty"` // ranges of pages that are cached
+
+	// not filled by GetPcStatus, the caller knows which processes it scanned
+	Processes []Holder `json:"processes,omitempty"` // processes holding the file open or mapped
+	MountNs   string   `json:"mount_ns,omitempty"`  // container or mount namespace the name is resolved in, empty for our own
+	Deleted   bool     `json:"deleted,omitempty"`   // the file is unlinked, only processes holding it can reach it
+	Aliases   []string `json:"aliases,omitempty"`   // other names of the same file, such as hardlinks and symlinked paths
 }
 
+// Holder is a process holding a file open or mapped.
+type Holder struct {
+	Pid        int    `json:"pid"`
+	Executable string `json:"executable"`
+}
+
+// KeepPageRanges makes GetPcStatus retain the ranges of cached pages of
+// each file. cachestat(2) only returns counters, so mincore is always used
+// when it's set.
+var KeepPageRanges bool
+
 func GetPcStatus(fname string, filter func(f *os.File) error) (PcStatus, error) {
+	return GetPcStatusByLink(fname, fname, filter)
+}
+
+// GetPcStatusByLink gets the page cache status of the file named fname by
+// opening link, such as /proc/<pid>/fd/<fd> or /proc/<pid>/map_files/<range>
+// of a process holding the file. it reaches the files that can't be opened
+// by name anymore, e.g. deleted files and memfd.
+func GetPcStatusByLink(fname, link string, filter func(f *os.File) error) (PcStatus, error) {
 	pcs := PcStatus{Name: fname}
 
-	f, err := os.Open(fname)
+	f, err := os.Open(link)
 	if err != nil {
 		return pcs, fmt.Errorf("could not open file for read: %v", err)
 	}
@@ -66,8 +104,40 @@ func GetPcStatus(fname string, filter func(f *os.File) error) (PcStatus, error)
 	pcs.Size = finfo.Size()
 	pcs.Timestamp = time.Now()
 	pcs.Mtime = finfo.ModTime()
+	if st, ok := finfo.Sys().(*syscall.Stat_t); ok {
+		pcs.Dev = uint64(st.Dev)
+		pcs.Inode = uint64(st.Ino)
+		pcs.Nlink = uint64(st.Nlink)
+	}
+
+	// prefer cachestat(2), it's cheaper than mmap + mincore and knows
+	// about dirty and writeback pages.
+	cstat, ok, err := GetFileCachestat(f)
+	if err != nil {
+		return pcs, err
+	}
+	if ok {
+		pageSize := int64(os.Getpagesize())
+		pcs.Pages = int((finfo.Size() + pageSize - 1) / pageSize)
+		pcs.Cached = int(cstat.Cached)
+		if pcs.Cached > pcs.Pages {
+			// pages beyond EOF may still be in the cache after a truncate.
+			pcs.Cached = pcs.Pages
+		}
+		pcs.Uncached = pcs.Pages - pcs.Cached
+		pcs.Dirty = int(cstat.Dirty)
+		pcs.Writeback = int(cstat.Writeback)
+		pcs.Evicted = int(cstat.Evicted)
+		pcs.RecentlyEvicted = int(cstat.RecentlyEvicted)
+		if pcs.Pages > 0 {
+			pcs.Percent = (float64(pcs.Cached) / float64(pcs.Pages)) * 100.00
+		}
+		if !KeepPageRanges {
+			return pcs, nil
+		}
+	}
 
-	mincore, err := GetFileMincore(f, finfo.Size())
+	mincore, err := getFileMincore(f, finfo.Size(), KeepPageRanges)
 	if err != nil {
 		return pcs, err
 	}
@@ -78,6 +148,7 @@ func GetPcStatus(fname string, filter func(f *os.File) error) (PcStatus, error)
 	pcs.Cached = int(mincore.Cached)
 	pcs.Pages = int(mincore.Cached) + int(mincore.Miss)
 	pcs.Uncached = int(mincore.Miss)
+	pcs.CachedRanges = mincore.Ranges
 
 	pcs.Percent = (float64(pcs.Cached) / float64(pcs.Pages)) * 100.00
 	return pcs, nil
@@ -0,0 +1,13 @@
+package pcstats
+
+import "golang.org/x/sys/unix"
+
+// readahead wraps readahead(2), the offset is split into the low and the
+// high word on 32 bit archs.
+func readahead(fd int, off, n int64) error {
+	_, _, errno := unix.Syscall6(unix.SYS_READAHEAD, uintptr(fd), uintptr(off), uintptr(off>>32), uintptr(n), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
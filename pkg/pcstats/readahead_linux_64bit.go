@@ -0,0 +1,16 @@
+//go:build linux && (amd64 || arm64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+// +build linux
+// +build amd64 arm64 mips64 mips64le ppc64 ppc64le riscv64 s390x
+
+package pcstats
+
+import "golang.org/x/sys/unix"
+
+// readahead wraps readahead(2), x/sys/unix has no wrapper for it.
+func readahead(fd int, off, n int64) error {
+	_, _, errno := unix.Syscall(unix.SYS_READAHEAD, uintptr(fd), uintptr(off), uintptr(n))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
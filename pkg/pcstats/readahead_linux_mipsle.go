@@ -0,0 +1,13 @@
+package pcstats
+
+import "golang.org/x/sys/unix"
+
+// readahead wraps readahead(2), the o32 abi passes the 64 bit offset in an
+// even register pair, low word first on little endian.
+func readahead(fd int, off, n int64) error {
+	_, _, errno := unix.Syscall6(unix.SYS_READAHEAD, uintptr(fd), 0, uintptr(off), uintptr(off>>32), uintptr(n), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
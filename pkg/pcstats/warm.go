@@ -0,0 +1,34 @@
+package pcstats
+
+import (
+	"fmt"
+)
+
+// WarmStrategy is the way of loading a file into the page cache.
+type WarmStrategy string
+
+const (
+	// WarmWillNeed asks the kernel to read the file asynchronously with
+	// posix_fadvise(POSIX_FADV_WILLNEED).
+	WarmWillNeed WarmStrategy = "willneed"
+
+	// WarmReadahead populates the page cache with readahead(2), it blocks
+	// until the pages have been read.
+	WarmReadahead WarmStrategy = "readahead"
+
+	// WarmRead reads every page of the file with pread(2), it works on any
+	// filesystem but copies the data into userspace.
+	WarmRead WarmStrategy = "read"
+)
+
+// warmChunkSize is the length of each request sent to the kernel, large
+// files are warmed chunk by chunk so the progress is reported while warming.
+const warmChunkSize = 2 << 20
+
+func ParseWarmStrategy(s string) (WarmStrategy, error) {
+	switch WarmStrategy(s) {
+	case WarmWillNeed, WarmReadahead, WarmRead:
+		return WarmStrategy(s), nil
+	}
+	return "", fmt.Errorf("unknown warm strategy %q, should be one of willneed, readahead and read", s)
+}
@@ -0,0 +1,89 @@
+package pcstats
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// WarmFile loads the whole file into the page cache, progress is called
+// with the number of bytes handled after each chunk, it may be nil.
+func WarmFile(fname string, strategy WarmStrategy, progress func(n int64)) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return fmt.Errorf("could not open file for read: %v", err)
+	}
+	defer f.Close()
+
+	finfo, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat file: %v", err)
+	}
+
+	return WarmRange(f, 0, finfo.Size(), strategy, progress)
+}
+
+// WarmRange loads length bytes of the file at off into the page cache.
+func WarmRange(f *os.File, off, length int64, strategy WarmStrategy, progress func(n int64)) error {
+	var buf []byte
+	if strategy == WarmRead {
+		buf = make([]byte, 1<<20)
+	}
+
+	end := off + length
+	for off < end {
+		n := min64(warmChunkSize, end-off)
+
+		var err error
+		switch strategy {
+		case WarmWillNeed:
+			err = unix.Fadvise(int(f.Fd()), off, n, unix.FADV_WILLNEED)
+			if err != nil {
+				err = fmt.Errorf("posix_fadvise WILLNEED failed: %v", err)
+			}
+		case WarmReadahead:
+			err = readahead(int(f.Fd()), off, n)
+			if err != nil {
+				err = fmt.Errorf("readahead failed: %v", err)
+			}
+		case WarmRead:
+			n, err = readRange(f, off, n, buf)
+		default:
+			err = fmt.Errorf("unknown warm strategy %q", strategy)
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// the file has been truncated.
+			return nil
+		}
+
+		if progress != nil {
+			progress(n)
+		}
+		off += n
+	}
+
+	return nil
+}
+
+// readRange reads length bytes at off and throws them away, it returns
+// the number of bytes actually read.
+func readRange(f *os.File, off, length int64, buf []byte) (int64, error) {
+	var total int64
+	for total < length {
+		size := min64(int64(len(buf)), length-total)
+		n, err := f.ReadAt(buf[:size], off+total)
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, fmt.Errorf("could not read file: %v", err)
+		}
+	}
+	return total, nil
+}
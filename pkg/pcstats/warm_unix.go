@@ -0,0 +1,16 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+
+package pcstats
+
+import (
+	"os"
+)
+
+func WarmFile(fname string, strategy WarmStrategy, progress func(n int64)) error {
+	return errNotSupported
+}
+
+func WarmRange(f *os.File, off, length int64, strategy WarmStrategy, progress func(n int64)) error {
+	return errNotSupported
+}
@@ -0,0 +1,153 @@
+package psutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupRoot is where the cgroup filesystems are mounted.
+const CgroupRoot = "/sys/fs/cgroup"
+
+// CgroupDir resolves a cgroup, such as 'system.slice/docker.service', to
+// its dir in the cgroup filesystem. both cgroup v2 and the memory hierarchy
+// of cgroup v1 are looked up, absolute paths under CgroupRoot are returned
+// as they are.
+func CgroupDir(cgroup string) (string, error) {
+	candidates := []string{cgroup}
+	if !strings.HasPrefix(cgroup, CgroupRoot+"/") {
+		candidates = []string{
+			filepath.Join(CgroupRoot, cgroup),            // v2
+			filepath.Join(CgroupRoot, "memory", cgroup),  // v1
+			filepath.Join(CgroupRoot, "unified", cgroup), // hybrid
+			filepath.Join(CgroupRoot, "systemd", cgroup), // hybrid
+		}
+	}
+
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("cgroup %q not found under %s", cgroup, CgroupRoot)
+}
+
+// CgroupPids returns the pids of the cgroup dir and all of its children.
+func CgroupPids(dir string) ([]int, error) {
+	var pids []int
+	err := filepath.Walk(dir, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the cgroup may have been removed while walking.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		procs, err := readPids(filepath.Join(fpath, "cgroup.procs"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		pids = append(pids, procs...)
+		return nil
+	})
+
+	return pids, err
+}
+
+func readPids(fname string) ([]int, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pids []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pid, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, scanner.Err()
+}
+
+// CgroupFileCache is the page cache charged to a cgroup, from memory.stat.
+type CgroupFileCache struct {
+	Dir     string
+	Cache   int64 // v2 'file', v1 'total_cache'
+	Mapped  int64 // v2 'file_mapped', v1 'total_mapped_file'
+	Dirty   int64 // v2 'file_dirty', v1 'total_dirty'
+	Shmem   int64 // v2 'shmem', v1 'total_shmem'
+	Version int
+}
+
+var errNoMemoryStat = errors.New("memory.stat not found, is the memory controller enabled?")
+
+// CgroupMemoryStat reads the file cache numbers of the cgroup dir, the
+// memory hierarchy of cgroup v1 is used when dir has no memory.stat.
+func CgroupMemoryStat(dir string) (*CgroupFileCache, error) {
+	candidates := []string{dir}
+	if rel, err := filepath.Rel(CgroupRoot, dir); err == nil {
+		// <root>/<hierarchy>/<path> of v1 and hybrid setups.
+		if parts := strings.SplitN(rel, string(filepath.Separator), 2); len(parts) == 2 {
+			candidates = append(candidates, filepath.Join(CgroupRoot, "memory", parts[1]))
+		}
+	}
+
+	for _, cdir := range candidates {
+		data, err := os.ReadFile(filepath.Join(cdir, "memory.stat"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseMemoryStat(cdir, string(data)), nil
+	}
+	return nil, errNoMemoryStat
+}
+
+func parseMemoryStat(dir, data string) *CgroupFileCache {
+	stat := make(map[string]int64)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		stat[fields[0]] = n
+	}
+
+	// v2 has 'file', v1 has 'cache' and the hierarchical 'total_*'.
+	if file, ok := stat["file"]; ok {
+		return &CgroupFileCache{
+			Dir:     dir,
+			Cache:   file,
+			Mapped:  stat["file_mapped"],
+			Dirty:   stat["file_dirty"],
+			Shmem:   stat["shmem"],
+			Version: 2,
+		}
+	}
+	return &CgroupFileCache{
+		Dir:     dir,
+		Cache:   stat["total_cache"],
+		Mapped:  stat["total_mapped_file"],
+		Dirty:   stat["total_dirty"],
+		Shmem:   stat["total_shmem"],
+		Version: 1,
+	}
+}
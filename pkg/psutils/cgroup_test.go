@@ -0,0 +1,65 @@
+package psutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMemoryStat(t *testing.T) {
+	for fname, version := range map[string]int{"memory.stat.v1": 1, "memory.stat.v2": 2} {
+		data, err := os.ReadFile(filepath.Join("testdata", "cgroup", fname))
+		assert.Nil(t, err)
+
+		// v1 must use the hierarchical total_* keys, not the local ones.
+		stat := parseMemoryStat("/cg", string(data))
+		assert.Equal(t, &CgroupFileCache{
+			Dir:     "/cg",
+			Cache:   73728000,
+			Mapped:  20480000,
+			Dirty:   409600,
+			Shmem:   4096000,
+			Version: version,
+		}, stat)
+	}
+
+	assert.Equal(t, &CgroupFileCache{Dir: "/cg", Version: 1}, parseMemoryStat("/cg", "garbage\nfile x\n"))
+}
+
+func TestCgroupMemoryStat(t *testing.T) {
+	dir := t.TempDir()
+	_, err := CgroupMemoryStat(dir)
+	assert.Equal(t, errNoMemoryStat, err)
+
+	data, err := os.ReadFile(filepath.Join("testdata", "cgroup", "memory.stat.v2"))
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "memory.stat"), data, 0644))
+
+	stat, err := CgroupMemoryStat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, dir, stat.Dir)
+	assert.Equal(t, 2, stat.Version)
+}
+
+func TestCgroupPids(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "c"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "cgroup.procs"), []byte("1\n2\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "a", "cgroup.procs"), []byte(""), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "a", "b", "cgroup.procs"), []byte("30\nbad\n 40 \n"), 0644))
+
+	pids, err := CgroupPids(root)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 30, 40}, pids)
+
+	pids, err = CgroupPids(filepath.Join(root, "a"))
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{30, 40}, pids)
+
+	pids, err = CgroupPids(filepath.Join(root, "missing"))
+	assert.Nil(t, err)
+	assert.Empty(t, pids)
+}
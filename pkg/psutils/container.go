@@ -0,0 +1,140 @@
+package psutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// container ids of docker, containerd, cri-o and podman are 64 hex chars,
+// they show up in the cgroup paths of the container processes, such as
+// '/docker/<id>', '/system.slice/docker-<id>.scope' and
+// '/kubepods/burstable/pod<uid>/cri-containerd-<id>.scope'.
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+var (
+	procRoot            = "/proc"
+	dockerContainersDir = "/var/lib/docker/containers"
+	podmanContainersDB  = "/var/lib/containers/storage/overlay-containers/containers.json"
+)
+
+// ContainerPids returns the pids of the processes running in the container,
+// id may be a prefix of the container id as long as it matches only one
+// container.
+func ContainerPids(id string) ([]int, error) {
+	d, err := os.Open(procRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make(map[string][]int)
+	for _, name := range names {
+		pid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+
+		// the process may have exited.
+		data, err := os.ReadFile(filepath.Join(procRoot, name, "cgroup"))
+		if err != nil {
+			continue
+		}
+
+		// the same id shows up once per cgroup v1 hierarchy.
+		seen := make(map[string]bool)
+		for _, cid := range containerIDRegexp.FindAllString(string(data), -1) {
+			if !strings.HasPrefix(cid, id) || seen[cid] {
+				continue
+			}
+			seen[cid] = true
+			containers[cid] = append(containers[cid], pid)
+		}
+	}
+
+	if len(containers) > 1 {
+		return nil, fmt.Errorf("ambiguous container id %q, it matches %d containers", id, len(containers))
+	}
+	for _, pids := range containers {
+		return pids, nil
+	}
+	return nil, nil
+}
+
+// ContainerID returns the id of the container running the process, found in
+// its cgroup, or "" when it's not in a container.
+func ContainerID(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	return containerIDRegexp.FindString(string(data))
+}
+
+// ResolveContainerID returns the id of the container with the name, from the
+// local state of docker and podman/cri-o. the name is returned as it is when
+// no container has it, it may be an id already.
+func ResolveContainerID(name string) string {
+	if id := dockerContainerID(name); id != "" {
+		return id
+	}
+	if id := podmanContainerID(name); id != "" {
+		return id
+	}
+	return strings.ToLower(name)
+}
+
+func dockerContainerID(name string) string {
+	configs, _ := filepath.Glob(filepath.Join(dockerContainersDir, "*", "config.v2.json"))
+	for _, config := range configs {
+		data, err := os.ReadFile(config)
+		if err != nil {
+			continue
+		}
+
+		var c struct {
+			ID   string
+			Name string
+		}
+		if err := json.Unmarshal(data, &c); err != nil {
+			continue
+		}
+		if strings.TrimPrefix(c.Name, "/") == strings.TrimPrefix(name, "/") {
+			return c.ID
+		}
+	}
+	return ""
+}
+
+func podmanContainerID(name string) string {
+	data, err := os.ReadFile(podmanContainersDB)
+	if err != nil {
+		return ""
+	}
+
+	var containers []struct {
+		ID    string   `json:"id"`
+		Names []string `json:"names"`
+	}
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return ""
+	}
+
+	for _, c := range containers {
+		for _, n := range c.Names {
+			if n == name {
+				return c.ID
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package psutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProc makes a /proc with the cgroup files of the pids.
+func fakeProc(t *testing.T, cgroups map[string]string) {
+	root := t.TempDir()
+	for pid, cgroup := range cgroups {
+		assert.Nil(t, os.MkdirAll(filepath.Join(root, pid), 0755))
+		if cgroup != "" {
+			assert.Nil(t, os.WriteFile(filepath.Join(root, pid, "cgroup"), []byte(cgroup), 0644))
+		}
+	}
+
+	old := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = old })
+}
+
+func TestContainerPids(t *testing.T) {
+	idA := "ab12" + strings.Repeat("a", 60)
+	idB := "ab34" + strings.Repeat("b", 60)
+	fakeProc(t, map[string]string{
+		// cgroup v1, the id shows up in every hierarchy.
+		"10":   "12:memory:/docker/" + idA + "\n11:cpu:/docker/" + idA + "\n",
+		"11":   "0::/system.slice/docker-" + idA + ".scope\n",
+		"12":   "0::/kubepods/burstable/pod1/cri-containerd-" + idB + ".scope\n",
+		"13":   "0::/user.slice/user-1000.slice\n",
+		"14":   "", // exited
+		"self": "0::/docker/" + idA + "\n",
+	})
+
+	pids, err := ContainerPids(idA)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{10, 11}, pids)
+
+	pids, err = ContainerPids("ab12")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{10, 11}, pids)
+
+	pids, err = ContainerPids("ab3")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{12}, pids)
+
+	_, err = ContainerPids("ab")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ambiguous container id")
+
+	pids, err = ContainerPids("ff")
+	assert.Nil(t, err)
+	assert.Empty(t, pids)
+
+	assert.Equal(t, idA, ContainerID(10))
+	assert.Equal(t, idB, ContainerID(12))
+	assert.Equal(t, "", ContainerID(13))
+	assert.Equal(t, "", ContainerID(14))
+}
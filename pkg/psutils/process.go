@@ -7,6 +7,8 @@
 // are interested.
 package psutils
 
+import "time"
+
 // Process is the generic interface that is implemented on every platform
 // and provides common operations for processes.
 type Process interface {
@@ -21,6 +23,16 @@ type Process interface {
 	// Executable name running this process. This is not a path to the
 	// executable.
 	Executable() string
+
+	// Cmdline is the arguments of the process, including argv[0]. It's
+	// empty for kernel threads.
+	Cmdline() []string
+
+	// Uid is the real user ID of the process, -1 when unknown.
+	Uid() int
+
+	// StartTime is when the process started.
+	StartTime() time.Time
 }
 
 type ProcessSlice []Process
@@ -50,5 +62,30 @@ func Processes() ([]Process, error) {
 // Process will be nil and error will be nil if a matching process is
 // not found.
 func FindProcess(pid int) (Process, error) {
-	return FindProcess(pid)
+	return findProcess(pid)
+}
+
+// Descendants returns the children of the process pid, their children and
+// so on, found by the parent pid of the given processes.
+func Descendants(pid int, procs []Process) []Process {
+	children := make(map[int][]Process, len(procs))
+	for _, proc := range procs {
+		// pid 0 is the parent of init and kthreadd.
+		if proc.Pid() == proc.PPid() {
+			continue
+		}
+		children[proc.PPid()] = append(children[proc.PPid()], proc)
+	}
+
+	var out []Process
+	queue := []int{pid}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, child := range children[parent] {
+			out = append(out, child)
+			queue = append(queue, child.Pid())
+		}
+	}
+	return out
 }
@@ -1,10 +1,24 @@
 package psutils
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+// userHZ is the unit of the times in /proc/{pid}/stat, it's fixed to 100
+// for userspace whatever CONFIG_HZ the kernel is built with.
+const userHZ = 100
+
+var (
+	bootTimeOnce sync.Once
+	bootTime     time.Time
 )
 
 // Refresh reloads all the data associated with this process.
@@ -33,6 +47,71 @@ func (p *UnixProcess) Refresh() error {
 		&p.ppid,
 		&p.pgrp,
 		&p.sid)
+	if err != nil {
+		return err
+	}
+
+	// starttime is the 22nd field, the fields after the image name start
+	// from the 3rd one.
+	if fields := strings.Fields(data); len(fields) > 19 {
+		ticks, _ := strconv.ParseInt(fields[19], 10, 64)
+		p.startTime = getBootTime().Add(time.Duration(ticks) * time.Second / userHZ)
+	}
+
+	// the process may exit at any time, cmdline and uid are best effort.
+	// kernel threads have no cmdline.
+	if cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", p.pid)); err == nil {
+		cmdline = bytes.TrimRight(cmdline, "\x00")
+		if len(cmdline) > 0 {
+			p.cmdline = strings.Split(string(cmdline), "\x00")
+		}
+	}
+	p.uid = readUid(fmt.Sprintf("/proc/%d/status", p.pid))
+
+	return nil
+}
+
+// readUid returns the real uid in the status file of a process, or -1.
+func readUid(fname string) int {
+	f, err := os.Open(fname)
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "Uid:" {
+			continue
+		}
+		uid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return -1
+		}
+		return uid
+	}
+	return -1
+}
 
-	return err
+// getBootTime returns the btime of /proc/stat, the times of processes are
+// relative to it.
+func getBootTime() time.Time {
+	bootTimeOnce.Do(func() {
+		data, err := ioutil.ReadFile("/proc/stat")
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, "btime ") {
+				continue
+			}
+			sec, err := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+			if err == nil {
+				bootTime = time.Unix(sec, 0)
+			}
+			break
+		}
+	})
+	return bootTime
 }
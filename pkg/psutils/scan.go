@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"time"
 )
 
 // UnixProcess is an implementation of Process that contains Unix-specific
@@ -16,8 +17,11 @@ type UnixProcess struct {
 	pgrp  int
 	sid   int
 	rss   int
+	uid   int
 
-	binary string
+	binary    string
+	cmdline   []string
+	startTime time.Time
 }
 
 func (p *UnixProcess) Pid() int {
@@ -36,6 +40,18 @@ func (p *UnixProcess) Executable() string {
 	return p.binary
 }
 
+func (p *UnixProcess) Cmdline() []string {
+	return p.cmdline
+}
+
+func (p *UnixProcess) Uid() int {
+	return p.uid
+}
+
+func (p *UnixProcess) StartTime() time.Time {
+	return p.startTime
+}
+
 func findProcess(pid int) (Process, error) {
 	dir := fmt.Sprintf("/proc/%d", pid)
 	_, err := os.Stat(dir)
@@ -99,6 +115,6 @@ func processes() ([]Process, error) {
 }
 
 func newUnixProcess(pid int) (*UnixProcess, error) {
-	p := &UnixProcess{pid: pid}
+	p := &UnixProcess{pid: pid, uid: -1}
 	return p, p.Refresh()
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// ProcessStat is the page cache usage of the files opened or mapped by a
+// process. a file shared by n processes counts fully in Cached of each of
+// them, but only 1/n of it counts in Apportioned, so the sum of Apportioned
+// is the real cached bytes.
+type ProcessStat struct {
+	Pid         int    `json:"pid"`
+	Executable  string `json:"executable"`
+	RSS         int64  `json:"rss"`         // resident memory in bytes
+	Files       int    `json:"files"`       // number of files
+	Shared      int    `json:"shared"`      // number of files also held by other processes
+	Size        int64  `json:"size"`        // total size of the files in bytes
+	Cached      int64  `json:"cached"`      // cached bytes of the files
+	Apportioned int64  `json:"apportioned"` // cached bytes of the files split among their holders
+}
+
+type ProcessStatList []ProcessStat
+
+// newProcessStatList sums the stats of the files of each process, sorted by
+// cached bytes. files without stats, e.g. filtered out, are ignored.
+func newProcessStatList(stats PcStatusList, procs []processFiles) ProcessStatList {
+	pageSize := int64(os.Getpagesize())
+	byName := make(map[string]int, len(stats))
+	for i, pcs := range stats {
+		byName[fileKey(pcs.MountNs, pcs.Name)] = i
+		for _, alias := range pcs.Aliases {
+			byName[alias] = i
+		}
+	}
+
+	// unique files of each process, and the number of holders of each file.
+	procUnique := make([][]int, len(procs))
+	holders := make(map[int]int)
+	for n, proc := range procs {
+		seen := make(map[int]emptyNull, len(proc.files))
+		for _, fname := range proc.files {
+			i, ok := byName[fileKey(proc.mntns, fname)]
+			if !ok {
+				continue
+			}
+			if _, dup := seen[i]; dup {
+				continue
+			}
+			seen[i] = emptyNull{}
+			procUnique[n] = append(procUnique[n], i)
+			holders[i]++
+		}
+	}
+
+	list := make(ProcessStatList, 0, len(procs))
+	for n, proc := range procs {
+		ps := ProcessStat{
+			Pid:        proc.pid,
+			Executable: proc.exe,
+			RSS:        proc.rss * pageSize,
+			Files:      len(procUnique[n]),
+		}
+		for _, i := range procUnique[n] {
+			cached := cachedSize(stats[i])
+			ps.Size += stats[i].Size
+			ps.Cached += cached
+			ps.Apportioned += cached / int64(holders[i])
+			if holders[i] > 1 {
+				ps.Shared++
+			}
+		}
+		list = append(list, ps)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Cached > list[j].Cached
+	})
+	return list
+}
+
+func (stats ProcessStatList) FormatText() {
+	stats.formatTable(textTable)
+}
+
+func (stats ProcessStatList) FormatUnicode() {
+	stats.formatTable(unicodeTable)
+}
+
+func (stats ProcessStatList) FormatPlain() {
+	stats.formatTable(plainTable)
+}
+
+func (stats ProcessStatList) formatTable(style tableStyle) {
+	header := []string{"Pid", "Executable", "RSS", "Files", "Shared", "Size", "Cached Size", "Apportioned", "Percent"}
+
+	var size_sum, cached_sum, apportioned_sum int64
+	rows := make([][]string, 0, len(stats))
+	for _, ps := range stats {
+		rows = append(rows, []string{
+			strconv.Itoa(ps.Pid),
+			ps.Executable,
+			ConvertUnit(ps.RSS),
+			strconv.Itoa(ps.Files),
+			strconv.Itoa(ps.Shared),
+			ConvertUnit(ps.Size),
+			ConvertUnit(ps.Cached),
+			ConvertUnit(ps.Apportioned),
+			fmt.Sprintf("%.3f", percentOf(ps.Cached, ps.Size)),
+		})
+
+		size_sum += ps.Size
+		cached_sum += ps.Cached
+		apportioned_sum += ps.Apportioned
+	}
+
+	// shared files are counted several times in size and cached, only the
+	// apportioned sum is meaningful.
+	sum := []string{"Sum", "", "", "", "", "", "", ConvertUnit(apportioned_sum), ""}
+	printTable(style, header, rows, sum)
+}
+
+func (stats ProcessStatList) FormatTerse() {
+	fmt.Println("pid,executable,rss,files,shared,size,cached,apportioned")
+	for _, ps := range stats {
+		fmt.Printf("%d,%s,%d,%d,%d,%d,%d,%d\n",
+			ps.Pid, ps.Executable, ps.RSS, ps.Files, ps.Shared, ps.Size, ps.Cached, ps.Apportioned)
+	}
+}
+
+func (stats ProcessStatList) FormatJson() {
+	writeJSON(stats, false)
+}
+
+func (stats ProcessStatList) FormatNDJson() {
+	writeJSON(stats, true)
+}
+
+func (pg *pgcacher) outputProcesses(stats ProcessStatList, limit int) {
+	limit = min(len(stats), limit)
+	stats = stats[:limit]
+
+	pg.format(stats)
+}
+
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100.00
+}
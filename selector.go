@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rfyiamcool/pgcacher/pkg/psutils"
+)
+
+// processSelector matches processes by name, command line and user, a
+// process has to match all of the given conditions.
+type processSelector struct {
+	names   []string       // wildcards of the executable name
+	cmdline *regexp.Regexp // matched against the args joined by spaces
+	uid     int            // -1 matches any user
+}
+
+// newProcessSelector parses the selectors, names are separated by commas
+// and user is a user name or uid.
+func newProcessSelector(names, cmdline, username string) (*processSelector, error) {
+	sel := &processSelector{uid: -1}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sel.names = append(sel.names, name)
+		}
+	}
+
+	if cmdline != "" {
+		re, err := regexp.Compile(cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cmdline pattern %q: %v", cmdline, err)
+		}
+		sel.cmdline = re
+	}
+
+	if username != "" {
+		uid, err := lookupUid(username)
+		if err != nil {
+			return nil, err
+		}
+		sel.uid = uid
+	}
+	return sel, nil
+}
+
+func lookupUid(username string) (int, error) {
+	if uid, err := strconv.Atoi(username); err == nil {
+		return uid, nil
+	}
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user %q: %v", username, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+func (sel *processSelector) match(proc psutils.Process) bool {
+	if sel.uid >= 0 && proc.Uid() != sel.uid {
+		return false
+	}
+
+	if len(sel.names) > 0 {
+		matched := false
+		for _, name := range sel.names {
+			if wildcardMatch(proc.Executable(), name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if sel.cmdline != nil {
+		args := proc.Cmdline()
+		if len(args) == 0 || !sel.cmdline.MatchString(strings.Join(args, " ")) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasProcessSelector returns true when processes are selected by name,
+// command line or user.
+func (pg *pgcacher) hasProcessSelector() bool {
+	return pg.option.processName != "" || pg.option.cmdline != "" || pg.option.user != ""
+}
+
+// appendSelectedFiles appends the files of all processes matching the
+// name, command line and user selectors.
+func (pg *pgcacher) appendSelectedFiles() error {
+	sel, err := newProcessSelector(pg.option.processName, pg.option.cmdline, pg.option.user)
+	if err != nil {
+		return err
+	}
+
+	procs, err := psutils.Processes()
+	if err != nil {
+		log.Fatalf("failed to get processes, err: %v", err)
+	}
+
+	var ps []psutils.Process
+	for _, proc := range procs {
+		// like pgrep, never match ourselves.
+		if proc.Pid() == os.Getpid() {
+			continue
+		}
+		if sel.match(proc) {
+			ps = append(ps, proc)
+		}
+	}
+	if len(ps) == 0 {
+		return errors.New("no process matches the process-name, cmdline and user selectors")
+	}
+
+	pg.appendProcessesFiles(ps)
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// snapshot records the cached pages of files, so that they can be loaded
+// into the page cache again later, e.g. after a reboot.
+type snapshot struct {
+	Time     time.Time      `json:"time"`
+	PageSize int64          `json:"page_size"`
+	Files    []snapshotFile `json:"files"`
+}
+
+type snapshotFile struct {
+	Path   string              `json:"path"`
+	Dev    uint64              `json:"dev"`
+	Inode  uint64              `json:"inode"`
+	Size   int64               `json:"size"`
+	Mtime  time.Time           `json:"mtime"`
+	Ranges []pcstats.PageRange `json:"ranges"`
+}
+
+// handleSnapshot writes the cached page ranges of the selected files to
+// the snapshot file.
+func (pg *pgcacher) handleSnapshot(fname string) {
+	stats := pg.getPageCacheStats()
+
+	snap := snapshot{
+		Time:     time.Now(),
+		PageSize: int64(os.Getpagesize()),
+		Files:    make([]snapshotFile, 0, len(stats)),
+	}
+	for _, status := range stats {
+		if len(status.CachedRanges) == 0 {
+			continue
+		}
+
+		// the snapshot is restored by path from our own mount namespace.
+		if status.MountNs != "" || status.Deleted {
+			log.Printf("skipping %q: not reachable by path from pgcacher's mount namespace", fileKey(status.MountNs, status.Name))
+			continue
+		}
+		if status.Inode == 0 {
+			log.Printf("skipping %q: unknown inode", status.Name)
+			continue
+		}
+
+		snap.Files = append(snap.Files, snapshotFile{
+			Path:   status.Name,
+			Dev:    status.Dev,
+			Inode:  status.Inode,
+			Size:   status.Size,
+			Mtime:  status.Mtime,
+			Ranges: status.CachedRanges,
+		})
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		log.Fatalf("JSON formatting failed: %s\n", err)
+	}
+	if err := os.WriteFile(fname, b, 0644); err != nil {
+		log.Fatalf("failed to write snapshot %q, err: %v", fname, err)
+	}
+
+	fmt.Printf("saved the cached pages of %d files to %s\n", len(snap.Files), fname)
+}
+
+// handleRestore loads the page ranges recorded in the snapshot file into
+// the page cache. files changed since the snapshot are skipped.
+func (pg *pgcacher) handleRestore(fname string, strategy pcstats.WarmStrategy) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		log.Fatalf("failed to read snapshot %q, err: %v", fname, err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		log.Fatalf("failed to parse snapshot %q, err: %v", fname, err)
+	}
+
+	var (
+		wg    = sync.WaitGroup{}
+		mu    = sync.Mutex{}
+		queue = make(chan snapshotFile, len(snap.Files))
+		files = make(PcStatusList, 0, len(snap.Files))
+	)
+
+	for _, file := range snap.Files {
+		if pg.ignoreFile(file.Path) {
+			continue
+		}
+		queue <- file
+	}
+	close(queue)
+
+	restore := func(file snapshotFile) error {
+		f, err := os.Open(file.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		finfo, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if err := file.verify(finfo); err != nil {
+			return err
+		}
+
+		for _, r := range file.Ranges {
+			off := r.Start * snap.PageSize
+			length := (r.End - r.Start) * snap.PageSize
+			if err := pcstats.WarmRange(f, off, length, strategy, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < pg.option.worker; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for file := range queue {
+				if err := restore(file); err != nil {
+					log.Printf("skipping %q: %v", file.Path, err)
+					continue
+				}
+
+				mu.Lock()
+				files = append(files, pcstats.PcStatus{Name: file.Path})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	pg.setFiles(files)
+	pg.output(pg.getPageCacheStats(), pg.option.limit)
+}
+
+// verify refuses files which have been replaced or modified since the
+// snapshot was taken, their recorded page ranges are meaningless.
+func (file snapshotFile) verify(finfo os.FileInfo) error {
+	if id, ok := statID(finfo); ok && id != (fileID{file.Dev, file.Inode}) {
+		return fmt.Errorf("device or inode changed since the snapshot, %d:%d != %d:%d", id.dev, id.inode, file.Dev, file.Inode)
+	}
+	if finfo.Size() != file.Size {
+		return fmt.Errorf("size changed since the snapshot, %d != %d", finfo.Size(), file.Size)
+	}
+	if !finfo.ModTime().Equal(file.Mtime) {
+		return fmt.Errorf("mtime changed since the snapshot, %s != %s", finfo.ModTime(), file.Mtime)
+	}
+	return nil
+}
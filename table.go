@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// tableStyle is the box characters of a table, tables without fill have no
+// horizontal lines.
+type tableStyle struct {
+	top, hr, bot [3]string // left, cross and right corners
+	fill         string    // horizontal line character
+	bar          string    // vertical separator
+}
+
+var (
+	textTable = tableStyle{
+		top:  [3]string{"+", "+", "+"},
+		hr:   [3]string{"|", "+", "|"},
+		bot:  [3]string{"+", "+", "+"},
+		fill: "-",
+		bar:  "|",
+	}
+	unicodeTable = tableStyle{
+		top:  [3]string{"┌", "┬", "┐"},
+		hr:   [3]string{"├", "┼", "┤"},
+		bot:  [3]string{"└", "┴", "┘"},
+		fill: "─",
+		bar:  "│",
+	}
+	plainTable = tableStyle{}
+)
+
+// printTable prints the rows with left aligned columns as wide as their
+// widest cell, the sum row is printed below a line when it's not nil.
+func printTable(style tableStyle, header []string, rows [][]string, sum []string) {
+	widths := make([]int, len(header))
+	for _, row := range append(append([][]string{header}, rows...), sum) {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	line := func(corners [3]string) {
+		if style.fill == "" {
+			return
+		}
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat(style.fill, w+2)
+		}
+		fmt.Println(corners[0] + strings.Join(parts, corners[1]) + corners[2])
+	}
+	printRow := func(row []string) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		if style.bar == "" {
+			fmt.Println(strings.TrimRight(strings.Join(cells, "  "), " "))
+			return
+		}
+		fmt.Printf("%s %s %s\n", style.bar, strings.Join(cells, " "+style.bar+" "), style.bar)
+	}
+
+	line(style.top)
+	printRow(header)
+	line(style.hr)
+	for _, row := range rows {
+		printRow(row)
+	}
+	if sum != nil {
+		line(style.hr)
+		printRow(sum)
+	}
+	line(style.bot)
+}
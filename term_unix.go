@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+
+package main
+
+import (
+	"errors"
+)
+
+var errTerminalNotSupported = errors.New("terminal is not supported on this platform")
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errTerminalNotSupported
+}
+
+func terminalSize(fd int) (int, int, error) {
+	return 0, 0, errTerminalNotSupported
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+	"golang.org/x/sys/unix"
+)
+
+// the number of entries read from a dir at once, huge dirs are never
+// loaded into memory as a whole.
+const readDirBatch = 256
+
+// dirWalker walks dirs with a pool of workers and streams the regular files
+// to a channel, so they can be measured while the dirs are still walked.
+// the entries are read without stat(2) when the filesystem tells their type.
+// the dirs on pseudo filesystems like proc and sysfs are never walked into,
+// only walked when given as the dirs to walk.
+type dirWalker struct {
+	workers        int
+	maxDepth       int  // the depth of dirs to walk, 0 means no limit
+	followSymlinks bool // walk the symlinked dirs and send the symlinked files
+	oneFS          bool // don't cross into other filesystems than the root's
+	ignore         func(fname string) bool
+
+	pseudoDevs map[uint64]emptyNull // devices of the pseudo filesystems
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	stack   []walkJob // pending dirs, walked depth first to bound the stack
+	pending int       // dirs in the stack or being read
+	visited map[fileID]emptyNull
+
+	skipped  int // unreadable dirs
+	firstErr error
+}
+
+type walkJob struct {
+	dir   string
+	depth int
+	dev   uint64 // device of the root, only with oneFS
+}
+
+func newDirWalker(workers, maxDepth int, followSymlinks, oneFS bool, ignore func(string) bool) *dirWalker {
+	w := &dirWalker{
+		workers:        workers,
+		maxDepth:       maxDepth,
+		followSymlinks: followSymlinks,
+		oneFS:          oneFS,
+		ignore:         ignore,
+		pseudoDevs:     pseudoFSDevs(),
+		visited:        make(map[fileID]emptyNull),
+	}
+	if w.workers <= 0 {
+		w.workers = 1
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// walk sends the files under the dirs to out and returns when all of them
+// are walked, out is left open. the dirs themselves are always followed
+// when they are symlinks, like find -H.
+func (w *dirWalker) walk(dirs []string, out chan<- string) {
+	for _, dir := range dirs {
+		finfo, err := os.Stat(dir)
+		if err != nil {
+			w.skip(dir, err)
+			continue
+		}
+		if !finfo.IsDir() {
+			out <- dir
+			continue
+		}
+
+		id, _ := statID(finfo)
+		if w.markVisited(id) {
+			w.push(walkJob{dir: dir, dev: id.dev})
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				job, ok := w.pop()
+				if !ok {
+					return
+				}
+				w.readDir(job, out)
+				w.done()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if w.skipped > 0 {
+		log.Printf("skipped %d unreadable dirs, such as: %v", w.skipped, w.firstErr)
+	}
+}
+
+func (w *dirWalker) push(job walkJob) {
+	w.mu.Lock()
+	w.stack = append(w.stack, job)
+	w.pending++
+	w.mu.Unlock()
+	w.cond.Signal()
+}
+
+// pop waits for a pending dir, it returns false when all dirs are walked.
+func (w *dirWalker) pop() (walkJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.stack) == 0 && w.pending > 0 {
+		w.cond.Wait()
+	}
+	if len(w.stack) == 0 {
+		return walkJob{}, false
+	}
+
+	job := w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+	return job, true
+}
+
+// done marks a dir popped as walked, its subdirs are pushed already.
+func (w *dirWalker) done() {
+	w.mu.Lock()
+	w.pending--
+	finished := w.pending == 0
+	w.mu.Unlock()
+
+	if finished {
+		w.cond.Broadcast()
+	}
+}
+
+func (w *dirWalker) skip(dir string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.firstErr == nil {
+		w.firstErr = err
+	}
+	w.skipped++
+}
+
+// markVisited returns false when the dir has been walked already, which
+// only matters when following symlinks, they may loop.
+func (w *dirWalker) markVisited(id fileID) bool {
+	if !w.followSymlinks {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.visited[id]; ok {
+		return false
+	}
+	w.visited[id] = emptyNull{}
+	return true
+}
+
+func (w *dirWalker) readDir(job walkJob, out chan<- string) {
+	f, err := os.Open(job.dir)
+	if err != nil {
+		w.skip(job.dir, err)
+		return
+	}
+	defer f.Close()
+
+	subdirs := w.maxDepth <= 0 || job.depth+1 < w.maxDepth
+	for {
+		entries, err := f.ReadDir(readDirBatch)
+		for _, entry := range entries {
+			fname := filepath.Join(job.dir, entry.Name())
+			typ := entry.Type()
+
+			if typ&os.ModeSymlink != 0 {
+				if !w.followSymlinks {
+					continue
+				}
+				finfo, err := os.Stat(fname)
+				if err != nil {
+					continue // dangling
+				}
+				typ = finfo.Mode().Type()
+			}
+
+			switch {
+			case typ.IsDir():
+				if subdirs {
+					w.pushDir(job, fname)
+				}
+			case typ.IsRegular():
+				if w.ignore == nil || !w.ignore(fname) {
+					out <- fname
+				}
+			}
+		}
+		if err != nil && err != io.EOF {
+			w.skip(job.dir, err)
+			return
+		}
+		if err == io.EOF || len(entries) == 0 {
+			return
+		}
+	}
+}
+
+// pushDir queues the subdir, unless it's on another filesystem with oneFS,
+// on a pseudo filesystem or it has been walked through a symlink already.
+func (w *dirWalker) pushDir(parent walkJob, dir string) {
+	var id fileID
+	if w.oneFS || w.followSymlinks || len(w.pseudoDevs) > 0 {
+		finfo, err := os.Stat(dir)
+		if err != nil {
+			w.skip(dir, err)
+			return
+		}
+		id, _ = statID(finfo)
+	}
+
+	if w.oneFS && id.dev != parent.dev {
+		return
+	}
+	if _, ok := w.pseudoDevs[id.dev]; ok {
+		return
+	}
+	if !w.markVisited(id) {
+		return
+	}
+	w.push(walkJob{dir: dir, depth: parent.depth + 1, dev: parent.dev})
+}
+
+// pseudoFSDevs returns the devices of the pseudo filesystems mounted, or nil
+// when the mounts can't be read.
+func pseudoFSDevs() map[uint64]emptyNull {
+	mounts, err := pcstats.ReadMounts()
+	if err != nil {
+		return nil
+	}
+
+	devs := make(map[uint64]emptyNull)
+	for _, m := range mounts {
+		if pcstats.IsPseudoFS(m.FSType) {
+			devs[unix.Mkdev(m.Major, m.Minor)] = emptyNull{}
+		}
+	}
+	return devs
+}
+
+func statID(finfo os.FileInfo) (fileID, bool) {
+	st, ok := finfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), inode: uint64(st.Ino)}, true
+}
+
+// splitDirs splits the paths given on the command line into dirs and the
+// others, which are measured as files and may not exist.
+func splitDirs(paths []string) ([]string, []string) {
+	var files, dirs []string
+	for _, fname := range paths {
+		if finfo, err := os.Stat(fname); err == nil && finfo.IsDir() {
+			dirs = append(dirs, fname)
+			continue
+		}
+		files = append(files, fname)
+	}
+	return files, dirs
+}
+
+// pruneCovered drops the dirs nested in another dir and the files under a
+// dir, the walker reaches them already. with a depth limit the nested dirs
+// are kept, the walk of their parent may stop above their files. the paths
+// are compared with the symlinks resolved, the walker doesn't follow the
+// symlinks below the dirs.
+func pruneCovered(files, dirs []string, maxDepth int) ([]string, []string) {
+	roots := make([]string, len(dirs))
+	for i, dir := range dirs {
+		roots[i] = realPath(dir)
+	}
+
+	// under returns the number of path elements of p below the first root
+	// other than skip containing it, or -1.
+	under := func(p string, skip int) int {
+		for i, root := range roots {
+			if i == skip || root == "" {
+				continue
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+				continue
+			}
+			if rel == "." {
+				if i < skip {
+					return 0 // the same dir given twice, keep the first
+				}
+				continue
+			}
+			return strings.Count(rel, "/") + 1
+		}
+		return -1
+	}
+
+	var pdirs []string
+	for i, dir := range dirs {
+		n := under(roots[i], i)
+		if n == 0 || (n > 0 && maxDepth <= 0) {
+			continue
+		}
+		pdirs = append(pdirs, dir)
+	}
+
+	var pfiles []string
+	for _, fname := range files {
+		real := realPath(fname)
+		if real != "" {
+			finfo, err := os.Stat(real)
+			n := under(real, -1)
+			if err == nil && finfo.Mode().IsRegular() && n > 0 && (maxDepth <= 0 || n <= maxDepth) {
+				continue
+			}
+		}
+		pfiles = append(pfiles, fname)
+	}
+	return pfiles, pdirs
+}
+
+// realPath returns the absolute path with the symlinks resolved, or "" when
+// the path doesn't exist.
+func realPath(fname string) string {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return ""
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return ""
+	}
+	return real
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rfyiamcool/pgcacher/pkg/pcstats"
+)
+
+// handleWarm loads the selected files into the page cache concurrently,
+// reports the progress while warming and the page cache stats at the end.
+func (pg *pgcacher) handleWarm(strategy pcstats.WarmStrategy) {
+	stats := pg.getPageCacheStats()
+
+	var (
+		total     int64
+		doneFiles int64
+		doneBytes int64
+		finished  = make(chan struct{})
+	)
+
+	for _, status := range stats {
+		total += status.Size
+	}
+
+	// report progress until all files are warmed.
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				log.Printf("warming: %d/%d files, %s/%s",
+					atomic.LoadInt64(&doneFiles), len(stats),
+					humanize.IBytes(uint64(atomic.LoadInt64(&doneBytes))), humanize.IBytes(uint64(total)))
+			case <-finished:
+				return
+			}
+		}
+	}()
+
+	progress := func(n int64) {
+		atomic.AddInt64(&doneBytes, n)
+	}
+
+	// warm files concurrently.
+	warmed := pg.forEachFile(stats, pg.option.worker, "warm", func(_ pcstats.PcStatus, fname string) error {
+		if err := pcstats.WarmFile(fname, strategy, progress); err != nil {
+			return err
+		}
+		atomic.AddInt64(&doneFiles, 1)
+		return nil
+	})
+	close(finished)
+
+	pg.setFiles(warmed)
+	pg.output(pg.getPageCacheStats(), pg.option.limit)
+}
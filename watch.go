@@ -0,0 +1,37 @@
+package main
+
+import "time"
+
+// handleWatch samples the stats of the same files every interval and prints
+// the change of each file since the previous sample, count is the number of
+// samples, 0 means forever.
+func (pg *pgcacher) handleWatch(interval time.Duration, count int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev PcStatusList
+	for i := 0; count <= 0 || i < count; i++ {
+		if i > 0 {
+			<-ticker.C
+		}
+
+		cur := pg.getPageCacheStats()
+		deltas := newPcStatusDeltaList(prev, cur)
+		prev = cur
+
+		pg.outputTitle(time.Now().Format("2006-01-02 15:04:05"))
+		pg.outputDelta(deltas, pg.option.limit)
+	}
+}
+
+func (pg *pgcacher) outputDelta(stats PcStatusDeltaList, limit int) {
+	limit = min(len(stats), limit)
+	stats = stats[:limit]
+
+	nstats := make(PcStatusDeltaList, len(stats))
+	for i, status := range stats {
+		status.PcStatus = pg.displayStatus(status.PcStatus)
+		nstats[i] = status
+	}
+	pg.format(nstats)
+}